@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/config"
+)
+
+// runCommand 执行命令行子命令, 不是子命令时返回false
+//
+//	api config check [配置文件]  输出生效的配置(密钥已隐藏)并校验, 配置有误时退出码为1
+func runCommand(args []string) bool {
+	if len(args) < 2 || args[0] != "config" || args[1] != "check" {
+		return false
+	}
+	file := ""
+	if len(args) > 2 {
+		file = args[2]
+	}
+	os.Exit(configCheck(file))
+	return true
+}
+
+// configCheck 输出生效的配置和全部校验错误, 返回进程退出码
+func configCheck(file string) int {
+	envConfig, err := config.Load(file)
+	if envConfig != nil {
+		if printErr := envConfig.Fprint(os.Stdout); printErr != nil {
+			fmt.Fprintln(os.Stderr, printErr)
+			return 1
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "\ninvalid config:\n%v\n", err)
+		return 1
+	}
+	fmt.Fprintln(os.Stderr, "\nconfig ok")
+	return 0
+}
@@ -1,14 +1,28 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"path/filepath"
 
 	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/config"
 	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/db"
 	_ "github.com/can4hou6joeng4/convenient-tools-project-v1-backend/docs" // 导入swagger文档
 	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/handlers"
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/logging"
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/mediacache"
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/metrics"
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/ratelimit"
 	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/repositories"
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/response"
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/service"
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/settings"
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/tracing"
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/upstream"
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
 	fiberSwagger "github.com/swaggo/fiber-swagger"
 )
 
@@ -23,14 +37,45 @@ import (
 // @host localhost:8082
 // @BasePath /api
 
+// @securityDefinitions.apikey AdminToken
+// @in header
+// @name Authorization
+// @description 管理接口令牌, 格式为 Bearer 令牌
+
 func main() {
+	if runCommand(os.Args[1:]) {
+		return
+	}
+
 	app := fiber.New(fiber.Config{
 		AppName:      "ConvenientTools",
 		ServerHeader: "Fiber",
+		ErrorHandler: response.ErrorHandler,
 	})
+	app.Use(metrics.Middleware())
+	app.Use(requestid.New())
+	app.Use(tracing.Middleware())
+	app.Use(logging.Middleware())
 
 	// Config
 	envConfig := config.NewEnvConfig()
+	app.Use(settings.Middleware(envConfig.AdminConfig.FlagTrustClientID))
+
+	// Logging
+	if err := logging.Init(os.Stdout, envConfig.LogConfig.LogLevel, envConfig.LogConfig.LogFormat); err != nil {
+		log.Fatalf("Unable to init logging: %v", err)
+	}
+
+	// Tracing
+	shutdownTracing, err := tracing.Init(context.Background(), tracing.Config{
+		Endpoint:    envConfig.TracingConfig.OtelExporterEndpoint,
+		ServiceName: envConfig.TracingConfig.OtelServiceName,
+		SampleRatio: envConfig.TracingConfig.OtelSampleRatio,
+	})
+	if err != nil {
+		log.Fatalf("Unable to init tracing: %v", err)
+	}
+	defer shutdownTracing(context.Background())
 	redis := db.InitRedis(envConfig)
 	cos := db.InitCOSClient(envConfig)
 	db := db.InitDatabase(envConfig, db.DBMigrator)
@@ -38,12 +83,109 @@ func main() {
 	// Swagger
 	app.Get("/swagger/*", fiberSwagger.WrapHandler)
 
+	// Metrics
+	sqlDB, err := db.DB()
+	if err != nil {
+		log.Fatalf("Unable to get database connection pool: %v", err)
+	}
+	metrics.RegisterDB(sqlDB, envConfig.DBConfig.DBName)
+	metrics.RegisterRedis(redis)
+	app.Get("/metrics", metrics.Handler())
+
 	// Repository
 	toolRepository := repositories.NewToolRepository(db)
+	settingRepository := repositories.NewSettingRepository(db)
+
+	// Runtime settings
+	settingsManager := settings.NewManager(settingRepository, redis)
+	if err := settingsManager.Load(context.Background()); err != nil {
+		log.Fatalf("Unable to load settings: %v", err)
+	}
+	settingsManager.Start(context.Background(), envConfig.AdminConfig.SettingsReloadInterval)
+	// 解析探测和请求处理都会读取运行时配置, 需在启动探测前设置
+	service.SetSettings(settingsManager)
+
+	// Upstream HTTP
+	hostPolicies, err := upstream.ParsePolicies(envConfig.UpstreamConfig.UpstreamHostPolicies)
+	if err != nil {
+		log.Fatalf("invalid UPSTREAM_HOST_POLICIES: %v", err)
+	}
+	proxyPools, err := upstream.ParseProxyPools(envConfig.UpstreamConfig.UpstreamProxyPools, envConfig.UpstreamConfig.UpstreamProxyStrategy, envConfig.UpstreamConfig.UpstreamProxyEjectDuration)
+	if err != nil {
+		log.Fatalf("invalid UPSTREAM_PROXY_POOLS: %v", err)
+	}
+	upstreamConfig := upstream.Config{
+		Default: upstream.HostPolicy{
+			Timeout:        envConfig.UpstreamConfig.UpstreamTimeout,
+			UserAgents:     []string{service.DesktopUserAgent},
+			MaxConcurrency: envConfig.UpstreamConfig.UpstreamMaxConcurrency,
+			MaxRetries:     envConfig.UpstreamConfig.UpstreamMaxRetries,
+		},
+		Policies:            service.HostPolicies(hostPolicies),
+		RetryBaseDelay:      envConfig.UpstreamConfig.UpstreamRetryBaseDelay,
+		RetryMaxDelay:       envConfig.UpstreamConfig.UpstreamRetryMaxDelay,
+		MaxIdleConnsPerHost: envConfig.UpstreamConfig.UpstreamMaxIdleConnsPerHost,
+		ProxyPools:          proxyPools,
+		DenyPrivateNetworks: true,
+		Breaker: upstream.BreakerConfig{
+			FailureThreshold: envConfig.UpstreamConfig.UpstreamBreakerFailureThreshold,
+			OpenDuration:     envConfig.UpstreamConfig.UpstreamBreakerOpenDuration,
+			HalfOpenRequests: envConfig.UpstreamConfig.UpstreamBreakerHalfOpenRequests,
+		},
+	}
+	if _, ok := proxyPools["default"]; ok {
+		upstreamConfig.Default.ProxyPool = "default"
+	}
+	if err := upstreamConfig.Validate(); err != nil {
+		log.Fatalf("invalid upstream config: %v", err)
+	}
+	for _, pool := range proxyPools {
+		pool.StartHealthCheck(context.Background(), envConfig.UpstreamConfig.UpstreamProxyCheckURL, envConfig.UpstreamConfig.UpstreamProxyCheckInterval)
+	}
+	upstreamClient := upstream.New(upstreamConfig)
+	service.SetHTTPClient(upstreamClient)
+
+	// Jobs
+	jobManager := service.NewJobManager(redis, envConfig.JobConfig.JobWorkers, envConfig.JobConfig.JobQueueSize, envConfig.JobConfig.JobTimeout, envConfig.JobConfig.JobRetention)
+
+	// Parse health probe
+	canaryLinks, err := service.ParseCanaryLinks(envConfig.ProbeConfig.ParseCanaryLinks)
+	if err != nil {
+		log.Fatalf("invalid PARSE_CANARY_LINKS: %v", err)
+	}
+	parseProber := service.NewParseProber(redis, canaryLinks, envConfig.ProbeConfig.ProbeInterval)
+	parseProber.Start(context.Background())
+
+	// Media cache
+	mediaCacheDir := envConfig.MediaConfig.MediaCacheDir
+	if mediaCacheDir == "" {
+		mediaCacheDir = filepath.Join(os.TempDir(), "convenient-tools-media-cache")
+	}
+	mediaCache, err := mediacache.New(mediacache.Config{
+		MemorySize:      int64(envConfig.MediaConfig.MediaCacheMemorySize) << 20,
+		MemoryItemLimit: int64(envConfig.MediaConfig.MediaCacheMemoryItemLimit) << 20,
+		DiskDir:         mediaCacheDir,
+		DiskSize:        int64(envConfig.MediaConfig.MediaCacheDiskSize) << 20,
+		TTL:             envConfig.MediaConfig.MediaCacheTTL,
+		PromoteHits:     envConfig.MediaConfig.MediaCachePromoteHits,
+	}, cos, redis)
+	if err != nil {
+		log.Fatalf("Unable to init media cache: %v", err)
+	}
 
 	// Routing
 	server := app.Group("/api")
-	handlers.NewCommonHandler(server, toolRepository, redis, cos, envConfig)
+	server.Use(ratelimit.Middleware(redis, func() int { return settingsManager.Get().RateLimit }))
+	handlers.NewCommonHandler(server, toolRepository, redis, cos, upstreamClient, settingsManager, mediaCache, envConfig)
+	handlers.NewPdfHandler(server, cos, envConfig)
+	handlers.NewConvertHandler(server, cos, jobManager, upstreamClient, settingsManager, envConfig)
+	handlers.NewJobHandler(server, jobManager)
+	handlers.NewImageHandler(server, redis, cos, envConfig)
+	handlers.NewParseStatusHandler(server, parseProber)
+
+	admin := server.Group("/admin", handlers.AdminAuth(envConfig.AdminConfig.AdminToken))
+	handlers.NewSettingsHandler(admin, settingsManager)
+	handlers.NewFlagHandler(admin, settingsManager)
 
 	app.Listen(fmt.Sprintf(":%s", envConfig.ServerPort))
 
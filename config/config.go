@@ -1,80 +1,280 @@
 package config
 
 import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"strings"
+	"time"
+
 	"github.com/caarlos0/env"
 	"github.com/gofiber/fiber/v2/log"
 	"github.com/joho/godotenv"
 )
 
 type EnvConfig struct {
-	ServerPort   string `env:"SERVER_PORT"`
-	MaxFileSize  int    `env:"MAX_FILE_SIZE"`
-	CosConfig    CosConfig
-	UploadConfig UploadConfig
-	RedisConfig  RedisConfig
-	DBConfig     DBConfig
+	ServerPort     string `env:"SERVER_PORT" envDefault:"8082"`
+	MaxFileSize    int    `env:"MAX_FILE_SIZE" envDefault:"50"` // 上传和下载文件的大小上限, 单位MB
+	CosConfig      CosConfig
+	UploadConfig   UploadConfig
+	RedisConfig    RedisConfig
+	DBConfig       DBConfig
+	ToolConfig     ToolConfig
+	JobConfig      JobConfig
+	MediaConfig    MediaConfig
+	ProbeConfig    ProbeConfig
+	UpstreamConfig UpstreamConfig
+	TracingConfig  TracingConfig
+	LogConfig      LogConfig
+	AdminConfig    AdminConfig
 }
 
 type CosConfig struct {
-	SecretID  string `env:"SECRET_ID"`
-	SecretKey string `env:"SECRET_KEY"`
+	SecretID  string `env:"SECRET_ID" secret:"true"`
+	SecretKey string `env:"SECRET_KEY" secret:"true"`
 	CosURL    string `env:"COS_URL"`
 	Bucket    string `env:"COS_BUCKET"`
 	Region    string `env:"COS_REGION"`
 }
 
 type UploadConfig struct {
-	AccessKeyId     string `env:"ALIBABA_CLOUD_ACCESS_KEY_ID"`
-	AccessKeySecret string `env:"ALIBABA_CLOUD_ACCESS_KEY_SECRET"`
+	AccessKeyId     string `env:"ALIBABA_CLOUD_ACCESS_KEY_ID" secret:"true"`
+	AccessKeySecret string `env:"ALIBABA_CLOUD_ACCESS_KEY_SECRET" secret:"true"`
 	EndPoint        string `env:"ALIBABA_CLOUD_END_POINT"`
 }
 
 type DBConfig struct {
 	DBHost         string `env:"DB_HOST"`
-	DBPort         string `env:"DB_PORT"`
+	DBPort         string `env:"DB_PORT" envDefault:"5432"`
 	DBUser         string `env:"DB_USER"`
-	DBPassword     string `env:"DB_PASSWORD"`
+	DBPassword     string `env:"DB_PASSWORD" secret:"true"`
 	DBName         string `env:"DB_NAME"`
-	DBSSLMode      string `env:"DB_SSL_MODE"`
-	DBMaxIdleConns int    `env:"DB_MAX_IDLE_CONNS"`
-	DBMaxOpenConns int    `env:"DB_MAX_OPEN_CONNS"`
+	DBSSLMode      string `env:"DB_SSL_MODE" envDefault:"disable"`
+	DBMaxIdleConns int    `env:"DB_MAX_IDLE_CONNS" envDefault:"10"`
+	DBMaxOpenConns int    `env:"DB_MAX_OPEN_CONNS" envDefault:"100"`
+	// DBConnMaxLifetime 连接的最长使用时间, DBConnMaxIdleTime 连接的最长空闲时间, 0 表示不限制
+	DBConnMaxLifetime time.Duration `env:"DB_CONN_MAX_LIFETIME" envDefault:"30m"`
+	DBConnMaxIdleTime time.Duration `env:"DB_CONN_MAX_IDLE_TIME" envDefault:"5m"`
+	// DBLogLevel GORM日志级别: silent、error、warn、info, info 输出所有SQL
+	DBLogLevel string `env:"DB_LOG_LEVEL" envDefault:"warn"`
+	// DBPrepareStmt 缓存预编译语句, 经PgBouncer事务模式连接时需关闭
+	DBPrepareStmt bool `env:"DB_PREPARE_STMT" envDefault:"true"`
+	// DBReplicaHosts 只读副本地址, 格式为 host:port, 多个以逗号分隔; 用户名、密码和库名与主库相同, 列表查询走副本
+	DBReplicaHosts string `env:"DB_REPLICA_HOSTS"`
+}
+
+// ToolConfig 外部命令行工具路径
+type ToolConfig struct {
+	FfmpegPath    string `env:"FFMPEG_PATH" envDefault:"ffmpeg"`
+	PdftoppmPath  string `env:"PDFTOPPM_PATH" envDefault:"pdftoppm"`
+	PdftotextPath string `env:"PDFTOTEXT_PATH" envDefault:"pdftotext"`
+	// SofficePath 为空或不可执行时关闭Office文档转换
+	SofficePath    string        `env:"SOFFICE_PATH"`
+	ConvertTimeout time.Duration `env:"CONVERT_TIMEOUT" envDefault:"2m"`
+}
+
+// JobConfig 异步任务配置
+type JobConfig struct {
+	JobWorkers   int           `env:"JOB_WORKERS" envDefault:"2"`
+	JobQueueSize int           `env:"JOB_QUEUE_SIZE" envDefault:"100"` // 排队等待执行的任务数上限, 超过时提交任务返回503
+	JobTimeout   time.Duration `env:"JOB_TIMEOUT" envDefault:"5m"`
+	JobRetention time.Duration `env:"JOB_RETENTION" envDefault:"24h"`
+}
+
+// MediaConfig 媒体代理与处理配置
+type MediaConfig struct {
+	ImageCacheTTL time.Duration `env:"IMAGE_CACHE_TTL" envDefault:"1h"`
+	ParseCacheTTL time.Duration `env:"PARSE_CACHE_TTL" envDefault:"1h"`
+	// WatermarkFilters 各平台去水印过滤器, 格式为 平台=crop|delogo:参数, 多个平台以分号分隔
+	WatermarkFilters string `env:"WATERMARK_FILTERS"`
+
+	// MediaCacheMemorySize 媒体代理内存缓存大小(MB), 不超过 MediaCacheMemoryItemLimit(MB) 的内容放入内存, 0 表示关闭
+	MediaCacheMemorySize      int `env:"MEDIA_CACHE_MEMORY_SIZE" envDefault:"128"`
+	MediaCacheMemoryItemLimit int `env:"MEDIA_CACHE_MEMORY_ITEM_LIMIT" envDefault:"2"`
+	// MediaCacheDir 磁盘缓存目录, 为空时使用系统临时目录; MediaCacheDiskSize 磁盘缓存大小(MB), 0 表示关闭
+	MediaCacheDir      string `env:"MEDIA_CACHE_DIR"`
+	MediaCacheDiskSize int    `env:"MEDIA_CACHE_DISK_SIZE" envDefault:"2048"`
+	// MediaCacheTTL 缓存内容的有效期
+	MediaCacheTTL time.Duration `env:"MEDIA_CACHE_TTL" envDefault:"1h"`
+	// MediaCachePromoteHits 单个实例命中次数达到该值时上传到对象存储供所有实例共享, 0 表示关闭
+	MediaCachePromoteHits int `env:"MEDIA_CACHE_PROMOTE_HITS"`
+}
+
+// ProbeConfig 平台解析健康探测配置
+type ProbeConfig struct {
+	// ParseCanaryLinks 各平台探测用的分享链接, 格式为 平台=链接, 多个平台以分号分隔, 为空时不探测
+	ParseCanaryLinks string        `env:"PARSE_CANARY_LINKS"`
+	ProbeInterval    time.Duration `env:"PROBE_INTERVAL" envDefault:"15m"`
+}
+
+// UpstreamConfig 请求平台接口和媒体CDN的出站HTTP配置
+type UpstreamConfig struct {
+	UpstreamTimeout             time.Duration `env:"UPSTREAM_TIMEOUT" envDefault:"30s"`
+	UpstreamMaxRetries          int           `env:"UPSTREAM_MAX_RETRIES" envDefault:"2"`
+	UpstreamRetryBaseDelay      time.Duration `env:"UPSTREAM_RETRY_BASE_DELAY" envDefault:"200ms"`
+	UpstreamRetryMaxDelay       time.Duration `env:"UPSTREAM_RETRY_MAX_DELAY" envDefault:"2s"`
+	UpstreamMaxConcurrency      int           `env:"UPSTREAM_MAX_CONCURRENCY"` // 每个域名的并发上限, 0 表示不限制
+	UpstreamMaxIdleConnsPerHost int           `env:"UPSTREAM_MAX_IDLE_CONNS_PER_HOST" envDefault:"16"`
+	// UpstreamHostPolicies 按域名或平台覆盖的策略, 格式为 域名:键=值,键=值, 多个域名以分号分隔
+	UpstreamHostPolicies string `env:"UPSTREAM_HOST_POLICIES"`
+	// UpstreamProxyPools 代理池, 格式为 池名=代理地址,代理地址, 多个池以分号分隔; 名为 default 的池默认用于所有请求
+	UpstreamProxyPools         string        `env:"UPSTREAM_PROXY_POOLS"`
+	UpstreamProxyStrategy      string        `env:"UPSTREAM_PROXY_STRATEGY" envDefault:"round_robin"` // round_robin 或 sticky
+	UpstreamProxyEjectDuration time.Duration `env:"UPSTREAM_PROXY_EJECT_DURATION" envDefault:"5m"`
+	// UpstreamProxyCheckURL 代理健康检查地址, 为空时不做健康检查, 被剔除的代理到期后自动恢复
+	UpstreamProxyCheckURL      string        `env:"UPSTREAM_PROXY_CHECK_URL"`
+	UpstreamProxyCheckInterval time.Duration `env:"UPSTREAM_PROXY_CHECK_INTERVAL" envDefault:"1m"`
+	// UpstreamBreakerFailureThreshold 同一域名连续失败多少次后熔断, 0 表示不启用
+	UpstreamBreakerFailureThreshold int           `env:"UPSTREAM_BREAKER_FAILURE_THRESHOLD" envDefault:"5"`
+	UpstreamBreakerOpenDuration     time.Duration `env:"UPSTREAM_BREAKER_OPEN_DURATION" envDefault:"30s"`
+	UpstreamBreakerHalfOpenRequests int           `env:"UPSTREAM_BREAKER_HALF_OPEN_REQUESTS" envDefault:"1"`
+}
+
+// TracingConfig OpenTelemetry链路追踪配置
+type TracingConfig struct {
+	// OtelExporterEndpoint OTLP/HTTP 接收地址, 如 http://otel-collector:4318, 为空时不导出追踪数据
+	OtelExporterEndpoint string  `env:"OTEL_EXPORTER_OTLP_ENDPOINT"`
+	OtelServiceName      string  `env:"OTEL_SERVICE_NAME" envDefault:"convenient-tools-api"`
+	OtelSampleRatio      float64 `env:"OTEL_TRACES_SAMPLE_RATIO" envDefault:"1"`
+}
+
+// LogConfig 日志配置
+type LogConfig struct {
+	// LogLevel 日志级别: debug、info、warn、error
+	LogLevel string `env:"LOG_LEVEL" envDefault:"info"`
+	// LogFormat 日志格式: json 或 text
+	LogFormat string `env:"LOG_FORMAT" envDefault:"json"`
+	// LogSlowQueryThreshold 超过该耗时的SQL以WARN级别输出
+	LogSlowQueryThreshold time.Duration `env:"LOG_SLOW_QUERY_THRESHOLD" envDefault:"200ms"`
+}
+
+// AdminConfig 管理接口和运行时配置
+type AdminConfig struct {
+	// AdminToken 管理接口的访问令牌, 请求头为 Authorization: Bearer 令牌, 为空时关闭管理接口
+	AdminToken string `env:"ADMIN_TOKEN" secret:"true"`
+	// SettingsReloadInterval 定期从数据库重新加载运行时配置的间隔, 避免错过修改通知
+	SettingsReloadInterval time.Duration `env:"SETTINGS_RELOAD_INTERVAL" envDefault:"1m"`
+	// FlagTrustClientID 功能开关灰度是否按 X-Client-ID 请求头分桶, 只应在网关会覆盖该请求头时开启, 否则使用客户端IP
+	FlagTrustClientID bool `env:"FLAG_TRUST_CLIENT_ID" envDefault:"false"`
 }
 
+// RedisConfig Redis连接配置
 type RedisConfig struct {
-	RedisHost     string `env:"REDIS_HOST"`
-	RedisPort     string `env:"REDIS_PORT"`
-	RedisPassword string `env:"REDIS_PASSWORD"`
-	RedisDB       int    `env:"REDIS_DB"`
+	// RedisMode 部署模式: standalone 单节点、sentinel 哨兵、cluster 集群
+	RedisMode string `env:"REDIS_MODE" envDefault:"standalone"`
+	RedisHost string `env:"REDIS_HOST"`
+	RedisPort string `env:"REDIS_PORT" envDefault:"6379"`
+	// RedisAddrs 哨兵或集群节点地址, 格式为 host:port, 多个以逗号分隔, 为空时使用 REDIS_HOST:REDIS_PORT
+	RedisAddrs string `env:"REDIS_ADDRS"`
+	// RedisMasterName 哨兵模式下的主节点名称
+	RedisMasterName string `env:"REDIS_MASTER_NAME"`
+	RedisUsername   string `env:"REDIS_USERNAME"`
+	RedisPassword   string `env:"REDIS_PASSWORD" secret:"true"`
+	// RedisSentinelPassword 哨兵节点的密码, 与数据节点不同时设置
+	RedisSentinelPassword string `env:"REDIS_SENTINEL_PASSWORD" secret:"true"`
+	// RedisDB 集群模式只支持0
+	RedisDB int `env:"REDIS_DB"`
+
+	// RedisTLS 使用TLS连接, RedisTLSCAFile 为自签名证书的CA文件, 为空时使用系统证书
+	RedisTLS                   bool   `env:"REDIS_TLS"`
+	RedisTLSCAFile             string `env:"REDIS_TLS_CA_FILE"`
+	RedisTLSInsecureSkipVerify bool   `env:"REDIS_TLS_INSECURE_SKIP_VERIFY"`
+
+	// RedisPoolSize 每个节点的最大连接数, 0 表示使用默认值(CPU核数的10倍)
+	RedisPoolSize     int           `env:"REDIS_POOL_SIZE"`
+	RedisMinIdleConns int           `env:"REDIS_MIN_IDLE_CONNS"`
+	RedisDialTimeout  time.Duration `env:"REDIS_DIAL_TIMEOUT" envDefault:"5s"`
+	RedisReadTimeout  time.Duration `env:"REDIS_READ_TIMEOUT" envDefault:"3s"`
+	RedisWriteTimeout time.Duration `env:"REDIS_WRITE_TIMEOUT" envDefault:"3s"`
+	// RedisPoolTimeout 连接池无空闲连接时的等待时间, 0 表示读超时加1秒
+	RedisPoolTimeout time.Duration `env:"REDIS_POOL_TIMEOUT"`
+
+	// RedisConnectRetries 启动时连接检查失败的重试次数, 重试间隔从 RedisConnectBackoff 开始逐次翻倍
+	RedisConnectRetries int           `env:"REDIS_CONNECT_RETRIES" envDefault:"5"`
+	RedisConnectBackoff time.Duration `env:"REDIS_CONNECT_BACKOFF" envDefault:"1s"`
 }
 
+// NewEnvConfig 加载配置, 配置有误时输出全部错误并终止启动
 func NewEnvConfig() *EnvConfig {
-	err := godotenv.Load()
+	config, err := Load("")
 	if err != nil {
-		log.Fatalf("Error loading .env file: %v", err)
+		log.Fatalf("Invalid config:\n%v", err)
+	}
+	return config
+}
+
+// Load 依次从环境变量、.env 文件和配置文件读取配置并校验
+//
+// 同一配置项以环境变量优先, 其次是 .env 文件, 最后是配置文件, 都未设置时使用默认值.
+// file 为空时读取 CONFIG_FILE 指定的配置文件. 配置有误时同时返回已读取的配置和全部错误
+func Load(file string) (*EnvConfig, error) {
+	if err := godotenv.Load(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("load .env: %w", err)
+	}
+	if file == "" {
+		file = os.Getenv("CONFIG_FILE")
+	}
+
+	var errs []error
+	if file != "" {
+		if err := loadFile(file); err != nil {
+			errs = append(errs, err)
+		}
 	}
 	config := &EnvConfig{}
-	if err := env.Parse(config); err != nil {
-		log.Fatalf("Error parsing env: %v", err)
+	for _, section := range config.sections() {
+		if err := env.Parse(section); err != nil {
+			errs = append(errs, err)
+		}
 	}
-	cosConfig := &CosConfig{}
-	if err := env.Parse(cosConfig); err != nil {
-		log.Fatalf("Error parsing env: %v", err)
+	if err := config.Validate(); err != nil {
+		errs = append(errs, err)
 	}
-	uploadConfig := &UploadConfig{}
-	if err := env.Parse(uploadConfig); err != nil {
-		log.Fatalf("Error parsing env: %v", err)
+	return config, errors.Join(errs...)
+}
+
+// sections 返回需要分别解析的配置结构, env 不会解析嵌套的结构体
+func (c *EnvConfig) sections() []interface{} {
+	return []interface{}{
+		c,
+		&c.CosConfig,
+		&c.UploadConfig,
+		&c.DBConfig,
+		&c.RedisConfig,
+		&c.ToolConfig,
+		&c.JobConfig,
+		&c.MediaConfig,
+		&c.ProbeConfig,
+		&c.UpstreamConfig,
+		&c.TracingConfig,
+		&c.LogConfig,
+		&c.AdminConfig,
 	}
-	dbConfig := &DBConfig{}
-	if err := env.Parse(dbConfig); err != nil {
-		log.Fatalf("Error parsing env: %v", err)
+}
+
+// ReplicaHosts 返回只读副本地址列表
+func (c DBConfig) ReplicaHosts() []string {
+	var hosts []string
+	for _, host := range strings.Split(c.DBReplicaHosts, ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			hosts = append(hosts, host)
+		}
 	}
-	redisConfig := &RedisConfig{}
-	if err := env.Parse(redisConfig); err != nil {
-		log.Fatalf("Error parsing env: %v", err)
+	return hosts
+}
+
+// Addrs 返回Redis节点地址列表
+func (c RedisConfig) Addrs() []string {
+	var addrs []string
+	for _, addr := range strings.Split(c.RedisAddrs, ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			addrs = append(addrs, addr)
+		}
 	}
-	config.CosConfig = *cosConfig
-	config.UploadConfig = *uploadConfig
-	config.RedisConfig = *redisConfig
-	config.DBConfig = *dbConfig
-	return config
+	if len(addrs) == 0 && c.RedisHost != "" {
+		addrs = append(addrs, net.JoinHostPort(c.RedisHost, c.RedisPort))
+	}
+	return addrs
 }
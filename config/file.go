@@ -0,0 +1,139 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// loadFile 读取YAML或TOML配置文件, 将其中的配置项写入未设置的环境变量
+//
+// 配置项名称与环境变量相同且不区分大小写, 嵌套的表以下划线连接, 如 db.host 对应 DB_HOST; 列表以逗号连接
+func loadFile(file string) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("read config file: %w", err)
+	}
+
+	values := map[string]interface{}{}
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &values)
+	case ".toml":
+		err = toml.Unmarshal(data, &values)
+	default:
+		return fmt.Errorf("unsupported config file %s, expected .yaml, .yml or .toml", file)
+	}
+	if err != nil {
+		return fmt.Errorf("parse config file %s: %w", file, err)
+	}
+
+	entries := map[string]string{}
+	if err := flatten("", values, entries); err != nil {
+		return fmt.Errorf("parse config file %s: %w", file, err)
+	}
+
+	known := (&EnvConfig{}).keys()
+	var errs []error
+	for _, key := range sortedKeys(entries) {
+		if !known[key] {
+			errs = append(errs, fmt.Errorf("%s: unknown config key in %s", key, file))
+			continue
+		}
+		if _, ok := os.LookupEnv(key); ok {
+			continue
+		}
+		os.Setenv(key, entries[key])
+	}
+	return errors.Join(errs...)
+}
+
+// flatten 将嵌套的配置展开为 环境变量名 => 值
+func flatten(prefix string, values map[string]interface{}, entries map[string]string) error {
+	for name, value := range values {
+		key := strings.ToUpper(name)
+		if prefix != "" {
+			key = prefix + "_" + key
+		}
+		if nested, ok := value.(map[string]interface{}); ok {
+			if err := flatten(key, nested, entries); err != nil {
+				return err
+			}
+			continue
+		}
+		str, err := configValue(value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		entries[key] = str
+	}
+	return nil
+}
+
+// configValue 将配置文件中的值转换为环境变量的字符串形式
+func configValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case uint64:
+		return strconv.FormatUint(v, 10), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case []interface{}:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			str, err := configValue(item)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, str)
+		}
+		return strings.Join(items, ","), nil
+	}
+	return "", fmt.Errorf("unsupported value type %T", value)
+}
+
+// keys 返回所有配置项的环境变量名
+func (c *EnvConfig) keys() map[string]bool {
+	keys := map[string]bool{}
+	for _, section := range c.sections() {
+		t := reflect.TypeOf(section).Elem()
+		for i := 0; i < t.NumField(); i++ {
+			if key := envKey(t.Field(i)); key != "" {
+				keys[key] = true
+			}
+		}
+	}
+	return keys
+}
+
+// envKey 返回字段对应的环境变量名, 没有 env 标签时返回空字符串
+func envKey(field reflect.StructField) string {
+	key, _, _ := strings.Cut(field.Tag.Get("env"), ",")
+	return key
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
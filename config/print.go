@@ -0,0 +1,42 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+)
+
+// masked 替换密钥等配置值的占位符
+const masked = "***"
+
+// userinfoReg 地址中的 用户名:密码@, 如代理地址
+var userinfoReg = regexp.MustCompile(`(\w+://[^\s:/@,;]+):[^\s@/,;]+@`)
+
+// Fprint 以 环境变量名=值 的形式输出生效的配置, 密钥和地址中的密码替换为 ***
+func (c *EnvConfig) Fprint(w io.Writer) error {
+	for _, section := range c.sections() {
+		v := reflect.ValueOf(section).Elem()
+		t := v.Type()
+		if _, err := fmt.Fprintf(w, "# %s\n", t.Name()); err != nil {
+			return err
+		}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			key := envKey(field)
+			if key == "" {
+				continue
+			}
+			value := fmt.Sprint(v.Field(i).Interface())
+			if field.Tag.Get("secret") == "true" && value != "" {
+				value = masked
+			} else {
+				value = userinfoReg.ReplaceAllString(value, "${1}:"+masked+"@")
+			}
+			if _, err := fmt.Fprintf(w, "%s=%s\n", key, value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,197 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Validate 检查必填项和取值格式, 返回全部错误
+func (c *EnvConfig) Validate() error {
+	v := &validator{}
+
+	v.port("SERVER_PORT", c.ServerPort)
+	v.positive("MAX_FILE_SIZE", c.MaxFileSize)
+
+	v.required("SECRET_ID", c.CosConfig.SecretID)
+	v.required("SECRET_KEY", c.CosConfig.SecretKey)
+	if v.required("COS_URL", c.CosConfig.CosURL) {
+		v.httpURL("COS_URL", c.CosConfig.CosURL)
+	}
+
+	v.required("DB_HOST", c.DBConfig.DBHost)
+	v.port("DB_PORT", c.DBConfig.DBPort)
+	v.required("DB_USER", c.DBConfig.DBUser)
+	v.required("DB_NAME", c.DBConfig.DBName)
+	v.oneOf("DB_SSL_MODE", c.DBConfig.DBSSLMode, "disable", "allow", "prefer", "require", "verify-ca", "verify-full")
+	v.nonNegative("DB_MAX_IDLE_CONNS", c.DBConfig.DBMaxIdleConns)
+	v.nonNegative("DB_MAX_OPEN_CONNS", c.DBConfig.DBMaxOpenConns)
+	v.nonNegativeDuration("DB_CONN_MAX_LIFETIME", c.DBConfig.DBConnMaxLifetime)
+	v.nonNegativeDuration("DB_CONN_MAX_IDLE_TIME", c.DBConfig.DBConnMaxIdleTime)
+	v.oneOf("DB_LOG_LEVEL", c.DBConfig.DBLogLevel, "silent", "error", "warn", "info")
+	v.hostPorts("DB_REPLICA_HOSTS", c.DBConfig.ReplicaHosts())
+
+	v.oneOf("REDIS_MODE", c.RedisConfig.RedisMode, "standalone", "sentinel", "cluster")
+	if c.RedisConfig.RedisAddrs == "" {
+		v.required("REDIS_HOST", c.RedisConfig.RedisHost)
+		v.port("REDIS_PORT", c.RedisConfig.RedisPort)
+	} else {
+		v.hostPorts("REDIS_ADDRS", c.RedisConfig.Addrs())
+	}
+	if c.RedisConfig.RedisMode == "standalone" && len(c.RedisConfig.Addrs()) > 1 {
+		v.errorf("REDIS_ADDRS", "standalone mode accepts a single address")
+	}
+	if c.RedisConfig.RedisMode == "sentinel" {
+		v.required("REDIS_MASTER_NAME", c.RedisConfig.RedisMasterName)
+	}
+	if c.RedisConfig.RedisMode == "cluster" && c.RedisConfig.RedisDB != 0 {
+		v.errorf("REDIS_DB", "must be 0 in cluster mode")
+	}
+	v.nonNegative("REDIS_DB", c.RedisConfig.RedisDB)
+	v.nonNegative("REDIS_POOL_SIZE", c.RedisConfig.RedisPoolSize)
+	v.nonNegative("REDIS_MIN_IDLE_CONNS", c.RedisConfig.RedisMinIdleConns)
+	v.duration("REDIS_DIAL_TIMEOUT", c.RedisConfig.RedisDialTimeout)
+	v.duration("REDIS_READ_TIMEOUT", c.RedisConfig.RedisReadTimeout)
+	v.duration("REDIS_WRITE_TIMEOUT", c.RedisConfig.RedisWriteTimeout)
+	v.nonNegativeDuration("REDIS_POOL_TIMEOUT", c.RedisConfig.RedisPoolTimeout)
+	v.nonNegative("REDIS_CONNECT_RETRIES", c.RedisConfig.RedisConnectRetries)
+	v.duration("REDIS_CONNECT_BACKOFF", c.RedisConfig.RedisConnectBackoff)
+	if c.RedisConfig.RedisTLSCAFile != "" {
+		if _, err := os.Stat(c.RedisConfig.RedisTLSCAFile); err != nil {
+			v.errorf("REDIS_TLS_CA_FILE", "%v", err)
+		}
+	}
+
+	v.duration("CONVERT_TIMEOUT", c.ToolConfig.ConvertTimeout)
+	v.positive("JOB_WORKERS", c.JobConfig.JobWorkers)
+	v.nonNegative("JOB_QUEUE_SIZE", c.JobConfig.JobQueueSize)
+	v.duration("JOB_TIMEOUT", c.JobConfig.JobTimeout)
+	v.duration("JOB_RETENTION", c.JobConfig.JobRetention)
+	v.duration("IMAGE_CACHE_TTL", c.MediaConfig.ImageCacheTTL)
+	v.duration("PARSE_CACHE_TTL", c.MediaConfig.ParseCacheTTL)
+	v.nonNegative("MEDIA_CACHE_MEMORY_SIZE", c.MediaConfig.MediaCacheMemorySize)
+	v.nonNegative("MEDIA_CACHE_MEMORY_ITEM_LIMIT", c.MediaConfig.MediaCacheMemoryItemLimit)
+	v.nonNegative("MEDIA_CACHE_DISK_SIZE", c.MediaConfig.MediaCacheDiskSize)
+	v.duration("MEDIA_CACHE_TTL", c.MediaConfig.MediaCacheTTL)
+	v.nonNegative("MEDIA_CACHE_PROMOTE_HITS", c.MediaConfig.MediaCachePromoteHits)
+	v.duration("PROBE_INTERVAL", c.ProbeConfig.ProbeInterval)
+
+	v.duration("UPSTREAM_TIMEOUT", c.UpstreamConfig.UpstreamTimeout)
+	v.nonNegative("UPSTREAM_MAX_RETRIES", c.UpstreamConfig.UpstreamMaxRetries)
+	v.duration("UPSTREAM_RETRY_BASE_DELAY", c.UpstreamConfig.UpstreamRetryBaseDelay)
+	v.duration("UPSTREAM_RETRY_MAX_DELAY", c.UpstreamConfig.UpstreamRetryMaxDelay)
+	v.nonNegative("UPSTREAM_MAX_CONCURRENCY", c.UpstreamConfig.UpstreamMaxConcurrency)
+	v.nonNegative("UPSTREAM_MAX_IDLE_CONNS_PER_HOST", c.UpstreamConfig.UpstreamMaxIdleConnsPerHost)
+	v.oneOf("UPSTREAM_PROXY_STRATEGY", c.UpstreamConfig.UpstreamProxyStrategy, "round_robin", "sticky")
+	v.duration("UPSTREAM_PROXY_EJECT_DURATION", c.UpstreamConfig.UpstreamProxyEjectDuration)
+	if c.UpstreamConfig.UpstreamProxyCheckURL != "" {
+		v.httpURL("UPSTREAM_PROXY_CHECK_URL", c.UpstreamConfig.UpstreamProxyCheckURL)
+		v.duration("UPSTREAM_PROXY_CHECK_INTERVAL", c.UpstreamConfig.UpstreamProxyCheckInterval)
+	}
+	v.nonNegative("UPSTREAM_BREAKER_FAILURE_THRESHOLD", c.UpstreamConfig.UpstreamBreakerFailureThreshold)
+	if c.UpstreamConfig.UpstreamBreakerFailureThreshold > 0 {
+		v.duration("UPSTREAM_BREAKER_OPEN_DURATION", c.UpstreamConfig.UpstreamBreakerOpenDuration)
+		v.positive("UPSTREAM_BREAKER_HALF_OPEN_REQUESTS", c.UpstreamConfig.UpstreamBreakerHalfOpenRequests)
+	}
+
+	if c.TracingConfig.OtelExporterEndpoint != "" {
+		v.httpURL("OTEL_EXPORTER_OTLP_ENDPOINT", c.TracingConfig.OtelExporterEndpoint)
+	}
+	if ratio := c.TracingConfig.OtelSampleRatio; ratio < 0 || ratio > 1 {
+		v.errorf("OTEL_TRACES_SAMPLE_RATIO", "must be between 0 and 1, got %v", ratio)
+	}
+
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.LogConfig.LogLevel)); err != nil {
+		v.errorf("LOG_LEVEL", "must be one of debug, info, warn, error, got %q", c.LogConfig.LogLevel)
+	}
+	v.oneOf("LOG_FORMAT", c.LogConfig.LogFormat, "json", "text")
+
+	v.duration("SETTINGS_RELOAD_INTERVAL", c.AdminConfig.SettingsReloadInterval)
+
+	return errors.Join(v.errs...)
+}
+
+// validator 收集校验错误, 每个错误以配置项名称开头
+type validator struct {
+	errs []error
+}
+
+func (v *validator) errorf(key, format string, args ...interface{}) {
+	v.errs = append(v.errs, fmt.Errorf("%s: %s", key, fmt.Sprintf(format, args...)))
+}
+
+// required 检查必填项, 已设置时返回true
+func (v *validator) required(key, value string) bool {
+	if strings.TrimSpace(value) == "" {
+		v.errorf(key, "is required")
+		return false
+	}
+	return true
+}
+
+func (v *validator) port(key, value string) {
+	if !v.required(key, value) {
+		return
+	}
+	if port, err := strconv.Atoi(value); err != nil || port < 1 || port > 65535 {
+		v.errorf(key, "must be a port number between 1 and 65535, got %q", value)
+	}
+}
+
+// hostPorts 检查地址列表中的每一项均为 host:port
+func (v *validator) hostPorts(key string, addrs []string) {
+	for _, addr := range addrs {
+		if host, port, err := net.SplitHostPort(addr); err != nil || host == "" {
+			v.errorf(key, "must be host:port, got %q", addr)
+		} else {
+			v.port(key, port)
+		}
+	}
+}
+
+func (v *validator) httpURL(key, value string) {
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		v.errorf(key, "must be an http or https url")
+	}
+}
+
+func (v *validator) oneOf(key, value string, allowed ...string) {
+	for _, a := range allowed {
+		if value == a {
+			return
+		}
+	}
+	v.errorf(key, "must be one of %s, got %q", strings.Join(allowed, ", "), value)
+}
+
+func (v *validator) positive(key string, value int) {
+	if value <= 0 {
+		v.errorf(key, "must be greater than 0, got %d", value)
+	}
+}
+
+func (v *validator) nonNegative(key string, value int) {
+	if value < 0 {
+		v.errorf(key, "must not be negative, got %d", value)
+	}
+}
+
+func (v *validator) nonNegativeDuration(key string, value time.Duration) {
+	if value < 0 {
+		v.errorf(key, "must not be negative, got %s", value)
+	}
+}
+
+func (v *validator) duration(key string, value time.Duration) {
+	if value <= 0 {
+		v.errorf(key, "must be a positive duration, got %s", value)
+	}
+}
@@ -6,5 +6,5 @@ import (
 )
 
 func DBMigrator(db *gorm.DB) error {
-	return db.AutoMigrate(&models.Tool{}, &models.Category{}, &models.Step{})
+	return db.AutoMigrate(&models.Tool{}, &models.Category{}, &models.Step{}, &models.Setting{})
 }
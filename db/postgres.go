@@ -2,40 +2,99 @@ package db
 
 import (
 	"fmt"
+	"net"
 
 	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/config"
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/logging"
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/tracing"
 	"github.com/gofiber/fiber/v2/log"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
+	"gorm.io/plugin/dbresolver"
 )
 
+// logLevels DB_LOG_LEVEL 对应的GORM日志级别
+var logLevels = map[string]logger.LogLevel{
+	"silent": logger.Silent,
+	"error":  logger.Error,
+	"warn":   logger.Warn,
+	"info":   logger.Info,
+}
+
 func InitDatabase(config *config.EnvConfig, DBMigrator func(*gorm.DB) error) *gorm.DB {
-	uri := fmt.Sprintf(`host=%s user=%s password=%s dbname=%s port=%s sslmode=%s`,
-		config.DBConfig.DBHost,
-		config.DBConfig.DBUser,
-		config.DBConfig.DBPassword,
-		config.DBConfig.DBName,
-		config.DBConfig.DBPort,
-		config.DBConfig.DBSSLMode,
-	)
+	dbConfig := config.DBConfig
 
-	log.Info("Connecting to database with uri: ", uri)
+	log.Infow("Connecting to database",
+		"host", dbConfig.DBHost,
+		"port", dbConfig.DBPort,
+		"db", dbConfig.DBName,
+		"user", dbConfig.DBUser,
+		"sslmode", dbConfig.DBSSLMode,
+		"replicas", dbConfig.ReplicaHosts(),
+	)
 
-	db, err := gorm.Open(postgres.Open(uri), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
+	db, err := gorm.Open(postgres.Open(dsn(dbConfig, dbConfig.DBHost, dbConfig.DBPort)), &gorm.Config{
+		Logger:      logging.GormLogger(config.LogConfig.LogSlowQueryThreshold).LogMode(logLevels[dbConfig.DBLogLevel]),
+		PrepareStmt: dbConfig.DBPrepareStmt,
 	})
 
 	if err != nil {
 		log.Fatalf("Unable to connect to database: %v", err)
 	}
 
+	sqlDB, err := db.DB()
+	if err != nil {
+		log.Fatalf("Unable to get database connection pool: %v", err)
+	}
+	sqlDB.SetMaxIdleConns(dbConfig.DBMaxIdleConns)
+	sqlDB.SetMaxOpenConns(dbConfig.DBMaxOpenConns)
+	sqlDB.SetConnMaxLifetime(dbConfig.DBConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(dbConfig.DBConnMaxIdleTime)
+
 	log.Info("Connected to the database")
 
+	if err := db.Use(tracing.GormPlugin()); err != nil {
+		log.Fatalf("Unable to register tracing plugin: %v", err)
+	}
+
 	if err := DBMigrator(db); err != nil {
 		log.Fatalf("Unable to migrate: %v", err)
 	}
 
+	// 配置只读副本后, 事务外的查询(如工具列表)随机发往副本, 写入和事务仍使用主库;
+	// 在迁移完成后注册, 避免迁移时从有延迟的副本读取表结构
+	if replicas := dbConfig.ReplicaHosts(); len(replicas) > 0 {
+		dialectors := make([]gorm.Dialector, 0, len(replicas))
+		for _, replica := range replicas {
+			host, port, _ := net.SplitHostPort(replica)
+			dialectors = append(dialectors, postgres.Open(dsn(dbConfig, host, port)))
+		}
+		resolver := dbresolver.Register(dbresolver.Config{
+			Replicas: dialectors,
+			Policy:   dbresolver.RandomPolicy{},
+		}).
+			SetMaxIdleConns(dbConfig.DBMaxIdleConns).
+			SetMaxOpenConns(dbConfig.DBMaxOpenConns).
+			SetConnMaxLifetime(dbConfig.DBConnMaxLifetime).
+			SetConnMaxIdleTime(dbConfig.DBConnMaxIdleTime)
+		if err := db.Use(resolver); err != nil {
+			log.Fatalf("Unable to register read replicas: %v", err)
+		}
+	}
+
 	return db
 }
+
+// dsn 返回连接 host:port 的连接串, 主库和只读副本使用相同的用户和库名
+func dsn(dbConfig config.DBConfig, host, port string) string {
+	return fmt.Sprintf(`host=%s user=%s password=%s dbname=%s port=%s sslmode=%s`,
+		host,
+		dbConfig.DBUser,
+		dbConfig.DBPassword,
+		dbConfig.DBName,
+		port,
+		dbConfig.DBSSLMode,
+	)
+}
@@ -1,15 +1,110 @@
 package db
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"time"
+
 	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/config"
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/tracing"
+	"github.com/gofiber/fiber/v2/log"
 	"github.com/redis/go-redis/v9"
 )
 
-func InitRedis(config *config.EnvConfig) *redis.Client {
-	RedisClient := redis.NewClient(&redis.Options{
-		Addr:     config.RedisConfig.RedisHost + ":" + config.RedisConfig.RedisPort,
-		Password: config.RedisConfig.RedisPassword,
-		DB:       config.RedisConfig.RedisDB,
-	})
+// redisMaxConnectBackoff 启动连接检查的最大重试间隔
+const redisMaxConnectBackoff = 30 * time.Second
+
+// InitRedis 按 REDIS_MODE 创建单节点、哨兵或集群客户端, 启动时检查连接, 重试后仍不可用时终止启动
+func InitRedis(config *config.EnvConfig) redis.UniversalClient {
+	redisConfig := config.RedisConfig
+
+	log.Infow("Connecting to redis",
+		"mode", redisConfig.RedisMode,
+		"addrs", redisConfig.Addrs(),
+		"master", redisConfig.RedisMasterName,
+		"db", redisConfig.RedisDB,
+		"tls", redisConfig.RedisTLS,
+	)
+
+	options := &redis.UniversalOptions{
+		Addrs:            redisConfig.Addrs(),
+		DB:               redisConfig.RedisDB,
+		Username:         redisConfig.RedisUsername,
+		Password:         redisConfig.RedisPassword,
+		SentinelPassword: redisConfig.RedisSentinelPassword,
+		MasterName:       redisConfig.RedisMasterName,
+		PoolSize:         redisConfig.RedisPoolSize,
+		MinIdleConns:     redisConfig.RedisMinIdleConns,
+		DialTimeout:      redisConfig.RedisDialTimeout,
+		ReadTimeout:      redisConfig.RedisReadTimeout,
+		WriteTimeout:     redisConfig.RedisWriteTimeout,
+		PoolTimeout:      redisConfig.RedisPoolTimeout,
+	}
+	if redisConfig.RedisTLS {
+		tlsConfig, err := redisTLSConfig(redisConfig)
+		if err != nil {
+			log.Fatalf("Invalid redis tls config: %v", err)
+		}
+		options.TLSConfig = tlsConfig
+	}
+
+	var RedisClient redis.UniversalClient
+	switch redisConfig.RedisMode {
+	case "sentinel":
+		RedisClient = redis.NewFailoverClient(options.Failover())
+	case "cluster":
+		RedisClient = redis.NewClusterClient(options.Cluster())
+	default:
+		RedisClient = redis.NewClient(options.Simple())
+	}
+
+	if err := pingRedis(RedisClient, redisConfig); err != nil {
+		log.Fatalf("Unable to connect to redis: %v", err)
+	}
+	log.Info("Connected to redis")
+
+	RedisClient.AddHook(tracing.RedisHook())
 	return RedisClient
 }
+
+// pingRedis 检查连接, 失败时按指数退避重试 RedisConnectRetries 次
+func pingRedis(client redis.UniversalClient, redisConfig config.RedisConfig) error {
+	backoff := redisConfig.RedisConnectBackoff
+	for attempt := 0; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), redisConfig.RedisDialTimeout+redisConfig.RedisReadTimeout)
+		err := client.Ping(ctx).Err()
+		cancel()
+		if err == nil {
+			return nil
+		}
+		if attempt >= redisConfig.RedisConnectRetries {
+			return err
+		}
+		log.Warnw("redis ping failed, retrying", "attempt", attempt+1, "retry_in", backoff.String(), "error", err)
+		time.Sleep(backoff)
+		backoff = min(backoff*2, redisMaxConnectBackoff)
+	}
+}
+
+// redisTLSConfig 返回连接Redis使用的TLS配置, 指定CA文件时只信任该CA
+func redisTLSConfig(redisConfig config.RedisConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: redisConfig.RedisTLSInsecureSkipVerify,
+	}
+	if redisConfig.RedisTLSCAFile != "" {
+		pem, err := os.ReadFile(redisConfig.RedisTLSCAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", redisConfig.RedisTLSCAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	return tlsConfig, nil
+}
@@ -19,56 +19,247 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/file/upload": {
-            "post": {
-                "description": "上传文件到服务器",
+        "/admin/flags": {
+            "get": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "返回所有已配置的功能开关, 未配置的功能默认开启",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "查询功能开关",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/response.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "object",
+                                            "additionalProperties": {
+                                                "$ref": "#/definitions/settings.Flag"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/flags/{name}": {
+            "put": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "修改后所有实例立即生效. 接口开关: media_proxy、media_bundle、convert_office、convert_transcode; 平台解析开关: parse, 在 platforms 中关闭单个平台",
                 "consumes": [
-                    "multipart/form-data"
+                    "application/json"
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "file"
+                    "admin"
                 ],
-                "summary": "上传文件",
+                "summary": "新增或修改功能开关",
                 "parameters": [
                     {
-                        "type": "file",
-                        "description": "要上传的文件",
-                        "name": "file",
-                        "in": "formData",
+                        "type": "string",
+                        "description": "开关名称",
+                        "name": "name",
+                        "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "开关配置",
+                        "name": "flag",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/settings.Flag"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/response.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "object",
+                                            "additionalProperties": {
+                                                "$ref": "#/definitions/settings.Flag"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "删除后该功能恢复默认开启",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "删除功能开关",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "开关名称",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/response.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "object",
+                                            "additionalProperties": {
+                                                "$ref": "#/definitions/settings.Flag"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
+                            "$ref": "#/definitions/response.Response"
                         }
                     }
                 }
             }
         },
-        "/tools": {
-            "post": {
-                "description": "创建一个新的工具",
+        "/admin/settings": {
+            "get": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "返回当前实例生效的运行时配置",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "查询运行时配置",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/response.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/settings.Settings"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/settings/{key}": {
+            "put": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
+                ],
+                "description": "请求体为配置项的JSON值, 保存后所有实例立即生效, 如 PUT /admin/settings/rate_limit 请求体为 120",
                 "consumes": [
                     "application/json"
                 ],
@@ -76,17 +267,31 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "tools"
+                    "admin"
                 ],
-                "summary": "创建新工具",
+                "summary": "修改运行时配置项",
                 "parameters": [
                     {
-                        "description": "工具信息",
-                        "name": "tool",
+                        "enum": [
+                            "rate_limit",
+                            "proxy_allowed_hosts",
+                            "user_agents",
+                            "transcode_profiles",
+                            "flags"
+                        ],
+                        "type": "string",
+                        "description": "配置项名称",
+                        "name": "key",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "配置项的值",
+                        "name": "value",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.Tool"
+                            "type": "object"
                         }
                     }
                 ],
@@ -94,88 +299,183 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/response.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/settings.Settings"
+                                        }
+                                    }
+                                }
+                            ]
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
+                            "$ref": "#/definitions/response.Response"
                         }
                     }
                 }
-            }
-        },
-        "/tools/download": {
-            "get": {
-                "description": "代理下载视频、图片等媒体资源，解决小程序环境中的合法域名限制问题",
-                "consumes": [
-                    "application/json"
+            },
+            "delete": {
+                "security": [
+                    {
+                        "AdminToken": []
+                    }
                 ],
                 "produces": [
-                    "application/octet-stream"
+                    "application/json"
                 ],
                 "tags": [
-                    "tools"
+                    "admin"
                 ],
-                "summary": "媒体文件下载代理",
+                "summary": "恢复运行时配置项的默认值",
                 "parameters": [
                     {
+                        "enum": [
+                            "rate_limit",
+                            "proxy_allowed_hosts",
+                            "user_agents",
+                            "transcode_profiles",
+                            "flags"
+                        ],
                         "type": "string",
-                        "description": "需要代理下载的媒体URL",
-                        "name": "url",
-                        "in": "query",
+                        "description": "配置项名称",
+                        "name": "key",
+                        "in": "path",
                         "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/response.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/settings.Settings"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
                     },
-                    {
-                        "type": "string",
-                        "description": "下载文件的文件名",
-                        "name": "filename",
-                        "in": "query"
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
                     },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/convert/office": {
+            "post": {
+                "description": "创建Office文档(docx/xlsx/pptx)转PDF的异步任务, 通过 /jobs/{id} 查询结果",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "convert"
+                ],
+                "summary": "Office文档转PDF",
+                "parameters": [
                     {
-                        "type": "boolean",
-                        "description": "对图片类型是否强制下载而非预览，默认false",
-                        "name": "forceDownload",
-                        "in": "query"
+                        "description": "文件key",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ConvertOfficeRequest"
+                        }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "文件内容",
+                    "202": {
+                        "description": "Accepted",
                         "schema": {
-                            "type": "file"
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/response.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/models.Job"
+                                        }
+                                    }
+                                }
+                            ]
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
+                            "$ref": "#/definitions/response.Response"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
                         }
                     }
                 }
             }
         },
-        "/tools/list": {
-            "get": {
-                "description": "获取系统中所有已注册的工具",
+        "/convert/transcode": {
+            "post": {
+                "description": "创建将远程视频转码为MP4的异步任务, 可合并分离的音视频流(如B站DASH), 可选按平台配置去除水印, 通过 /jobs/{id} 查询结果",
                 "consumes": [
                     "application/json"
                 ],
@@ -183,118 +483,1489 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "tools"
+                    "convert"
+                ],
+                "summary": "视频转码",
+                "parameters": [
+                    {
+                        "description": "视频地址和转码选项",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ConvertTranscodeRequest"
+                        }
+                    }
                 ],
-                "summary": "获取所有工具列表",
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "202": {
+                        "description": "Accepted",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/response.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/models.Job"
+                                        }
+                                    }
+                                }
+                            ]
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
                         }
                     }
                 }
             }
         },
-        "/tools/wechat-download-config": {
-            "get": {
-                "description": "根据文件URL生成微信小程序下载所需的配置信息，包括权限要求和API调用建议",
+        "/file/upload": {
+            "post": {
+                "description": "上传文件到服务器, 支持pdf/docx/xlsx/pptx",
                 "consumes": [
-                    "application/json"
+                    "multipart/form-data"
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "tools"
+                    "file"
                 ],
-                "summary": "获取微信小程序下载配置信息",
+                "summary": "上传文件",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "需要下载的媒体URL",
-                        "name": "url",
-                        "in": "query",
+                        "type": "file",
+                        "description": "要上传的文件",
+                        "name": "file",
+                        "in": "formData",
                         "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "下载文件的文件名",
-                        "name": "filename",
-                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/response.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/handlers.UploadData"
+                                        }
+                                    }
+                                }
+                            ]
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
+                            "$ref": "#/definitions/response.Response"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
+                            "$ref": "#/definitions/response.Response"
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "models.Step": {
-            "type": "object",
-            "properties": {
-                "created_at": {
-                    "type": "string"
-                },
-                "desc": {
-                    "type": "string"
-                },
-                "id": {
-                    "type": "integer"
-                },
-                "order": {
-                    "type": "integer"
-                },
-                "title": {
-                    "type": "string"
-                },
-                "updated_at": {
-                    "type": "string"
-                }
-            }
         },
-        "models.Tool": {
-            "type": "object",
-            "properties": {
-                "created_at": {
-                    "type": "string"
-                },
+        "/image/process": {
+            "post": {
+                "description": "对已上传的图片进行缩放、裁剪、按EXIF方向旋转、格式转换(JPEG/PNG/WebP)和压缩, 结果写回对象存储",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "image"
+                ],
+                "summary": "处理已上传的图片",
+                "parameters": [
+                    {
+                        "description": "文件key和处理参数",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ImageProcessRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/response.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/response.KeyData"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/jobs/{id}": {
+            "get": {
+                "description": "查询异步任务的执行状态和结果文件key",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "jobs"
+                ],
+                "summary": "查询异步任务",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "任务ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/response.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/models.Job"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/pdf/compress": {
+            "post": {
+                "description": "优化PDF结构以减小文件体积",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "pdf"
+                ],
+                "summary": "压缩PDF",
+                "parameters": [
+                    {
+                        "description": "文件key",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.PdfKeyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/response.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/response.KeyData"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/pdf/images": {
+            "post": {
+                "description": "将PDF页面渲染为PNG/JPEG图片",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "pdf"
+                ],
+                "summary": "PDF转图片",
+                "parameters": [
+                    {
+                        "description": "文件key和渲染参数",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.PdfImagesRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/response.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/response.KeysData"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/pdf/merge": {
+            "post": {
+                "description": "按顺序合并多个已上传的PDF文件, 结果写回对象存储",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "pdf"
+                ],
+                "summary": "合并PDF",
+                "parameters": [
+                    {
+                        "description": "待合并的文件key",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.PdfMergeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/response.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/response.KeyData"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/pdf/split": {
+            "post": {
+                "description": "按页码范围拆分PDF, 每个范围生成一个新文件",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "pdf"
+                ],
+                "summary": "拆分PDF",
+                "parameters": [
+                    {
+                        "description": "文件key和页码范围",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.PdfSplitRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/response.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/response.KeysData"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/pdf/text": {
+            "post": {
+                "description": "提取PDF中的纯文本, 结果以txt文件写回对象存储",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "pdf"
+                ],
+                "summary": "提取PDF文本",
+                "parameters": [
+                    {
+                        "description": "文件key",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.PdfKeyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/response.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/response.KeyData"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/pdf/watermark": {
+            "post": {
+                "description": "为PDF的指定页面添加文字水印",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "pdf"
+                ],
+                "summary": "PDF添加文字水印",
+                "parameters": [
+                    {
+                        "description": "文件key和水印参数",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.PdfWatermarkRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/response.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/response.KeyData"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/tools": {
+            "post": {
+                "description": "创建一个新的工具",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tools"
+                ],
+                "summary": "创建新工具",
+                "parameters": [
+                    {
+                        "description": "工具信息",
+                        "name": "tool",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Tool"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/tools/list": {
+            "get": {
+                "description": "获取系统中所有已注册的工具",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tools"
+                ],
+                "summary": "获取所有工具列表",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/response.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/models.Tool"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/tools/media-bundle": {
+            "get": {
+                "description": "将解析结果中的图集图片(含实况视频片段)、封面以及可选的背景音乐边下载边打包为ZIP返回, 通过解析接口返回的cache_key或分享链接指定内容",
+                "produces": [
+                    "application/zip"
+                ],
+                "tags": [
+                    "tools"
+                ],
+                "summary": "打包下载图集",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "解析接口返回的cache_key",
+                        "name": "key",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "分享链接, 未提供key时实时解析",
+                        "name": "url",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "是否包含背景音乐",
+                        "name": "music",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "ZIP文件",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/tools/media-proxy": {
+            "get": {
+                "description": "从远程服务器获取媒体文件并转发给客户端，解决小程序域名限制问题，支持视频格式转换",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/octet-stream"
+                ],
+                "tags": [
+                    "tools"
+                ],
+                "summary": "代理媒体文件",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "媒体文件URL",
+                        "name": "url",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "媒体类型(video/image)",
+                        "name": "type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "输出格式(视频: mp4/webm/mov; 图片: jpeg/png/webp)",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "图片目标宽度",
+                        "name": "w",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "图片目标高度",
+                        "name": "h",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "图片缩放模式(contain/cover/fill)",
+                        "name": "fit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "图片裁剪区域(x,y,w,h)",
+                        "name": "crop",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "图片压缩质量(1-100)",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "按平台配置去除水印",
+                        "name": "clean",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "备用地址, 原地址失败时依次尝试, 可重复",
+                        "name": "fallback",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "上次响应的ETag, 未变化时返回304",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "上次响应的Last-Modified, 未变化时返回304",
+                        "name": "If-Modified-Since",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "媒体文件",
+                        "schema": {
+                            "type": "file"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "原样返回时为源站ETag, 处理后的内容为内容摘要"
+                            },
+                            "Last-Modified": {
+                                "type": "string",
+                                "description": "原样返回时为源站的修改时间"
+                            },
+                            "X-Cache": {
+                                "type": "string",
+                                "description": "内容来源: memory、disk、cos 或 miss(回源)"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "内容未变化"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "405": {
+                        "description": "Method Not Allowed",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/tools/parse": {
+            "post": {
+                "description": "解析各平台分享链接或分享文案, 返回视频/图集地址、封面和作者信息",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tools"
+                ],
+                "summary": "解析视频分享链接",
+                "parameters": [
+                    {
+                        "description": "分享链接",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ParseShareUrlRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/handlers.ParseShareUrlResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/models.VideoParseInfo"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "504": {
+                        "description": "Gateway Timeout",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/tools/parse/status": {
+            "get": {
+                "description": "返回各平台最近一次探测链接解析结果、支持的平台和分享链接域名, 以及出站请求的熔断状态",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tools"
+                ],
+                "summary": "查询平台解析健康状态",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/response.Response"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/models.ParseStatus"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "handlers.ConvertOfficeRequest": {
+            "type": "object",
+            "properties": {
+                "key": {
+                    "description": "已上传的docx/xlsx/pptx文件key",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.ConvertTranscodeRequest": {
+            "type": "object",
+            "properties": {
+                "audio_url": {
+                    "description": "音频地址, 音视频分离(DASH)时与视频合并",
+                    "type": "string"
+                },
+                "clean": {
+                    "description": "是否按平台配置去除水印",
+                    "type": "boolean"
+                },
+                "profile": {
+                    "description": "转码参数名称, 为空时使用 default",
+                    "type": "string"
+                },
+                "url": {
+                    "description": "视频地址",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.ImageProcessRequest": {
+            "type": "object",
+            "properties": {
+                "crop": {
+                    "description": "缩放前裁剪区域, 格式为 x,y,w,h",
+                    "type": "string"
+                },
+                "fit": {
+                    "description": "缩放模式: contain/cover/fill",
+                    "type": "string"
+                },
+                "format": {
+                    "description": "输出格式: jpeg/png/webp, 为空时保持原格式",
+                    "type": "string"
+                },
+                "h": {
+                    "description": "目标高度, 0表示按比例",
+                    "type": "integer"
+                },
+                "key": {
+                    "description": "已上传的图片文件key",
+                    "type": "string"
+                },
+                "q": {
+                    "description": "压缩质量(1-100), 默认80",
+                    "type": "integer"
+                },
+                "w": {
+                    "description": "目标宽度, 0表示按比例",
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.ParseShareUrlRequest": {
+            "type": "object",
+            "properties": {
+                "url": {
+                    "description": "分享链接或包含链接的分享文案",
+                    "type": "string",
+                    "example": "https://v.douyin.com/iFRvNakm/"
+                }
+            }
+        },
+        "handlers.ParseShareUrlResponse": {
+            "type": "object",
+            "properties": {
+                "cache_key": {
+                    "type": "string"
+                },
+                "code": {
+                    "description": "错误码, 仅失败时返回",
+                    "type": "string",
+                    "example": "invalid_link"
+                },
+                "data": {},
+                "message": {
+                    "type": "string",
+                    "example": "success"
+                },
+                "request_id": {
+                    "description": "请求ID, 仅失败时返回, 用于排查日志",
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "success"
+                }
+            }
+        },
+        "handlers.PdfImagesRequest": {
+            "type": "object",
+            "properties": {
+                "dpi": {
+                    "description": "分辨率, 默认150",
+                    "type": "integer"
+                },
+                "first_page": {
+                    "description": "起始页, 0表示第一页",
+                    "type": "integer"
+                },
+                "format": {
+                    "description": "png/jpeg, 默认png",
+                    "type": "string"
+                },
+                "key": {
+                    "type": "string"
+                },
+                "last_page": {
+                    "description": "结束页, 0表示最后一页",
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.PdfKeyRequest": {
+            "type": "object",
+            "properties": {
+                "key": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.PdfMergeRequest": {
+            "type": "object",
+            "properties": {
+                "keys": {
+                    "description": "按顺序合并的PDF文件key",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "handlers.PdfSplitRequest": {
+            "type": "object",
+            "properties": {
+                "key": {
+                    "type": "string"
+                },
+                "ranges": {
+                    "description": "页码范围, 如 [\"1-3\", \"4-\"]",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "handlers.PdfWatermarkRequest": {
+            "type": "object",
+            "properties": {
+                "key": {
+                    "type": "string"
+                },
+                "opacity": {
+                    "description": "不透明度(0-1], 默认0.3",
+                    "type": "number"
+                },
+                "pages": {
+                    "description": "页码范围, 为空时处理所有页面",
+                    "type": "string"
+                },
+                "text": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.UploadData": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "description": "原始文件名",
+                    "type": "string"
+                },
+                "url": {
+                    "description": "文件key",
+                    "type": "string"
+                }
+            }
+        },
+        "models.CircuitBreaker": {
+            "type": "object",
+            "properties": {
+                "consecutive_failures": {
+                    "type": "integer"
+                },
+                "host": {
+                    "type": "string"
+                },
+                "opened_at": {
+                    "description": "最近一次熔断时间",
+                    "type": "string"
+                },
+                "state": {
+                    "description": "closed、open 或 half_open",
+                    "type": "string"
+                },
+                "trips": {
+                    "description": "累计熔断次数",
+                    "type": "integer"
+                }
+            }
+        },
+        "models.Job": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "error": {
+                    "description": "失败原因",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "params": {
+                    "description": "任务参数",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "result_key": {
+                    "description": "结果文件key",
+                    "type": "string"
+                },
+                "status": {
+                    "$ref": "#/definitions/models.JobStatus"
+                },
+                "type": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.JobStatus": {
+            "type": "string",
+            "enum": [
+                "pending",
+                "running",
+                "succeeded",
+                "failed"
+            ],
+            "x-enum-comments": {
+                "JobStatusFailed": "执行失败",
+                "JobStatusPending": "排队中",
+                "JobStatusRunning": "执行中",
+                "JobStatusSucceeded": "执行成功"
+            },
+            "x-enum-varnames": [
+                "JobStatusPending",
+                "JobStatusRunning",
+                "JobStatusSucceeded",
+                "JobStatusFailed"
+            ]
+        },
+        "models.LivePhoto": {
+            "type": "object",
+            "properties": {
+                "index": {
+                    "description": "对应 Images 中的下标",
+                    "type": "integer"
+                },
+                "video_url": {
+                    "description": "视频片段地址",
+                    "type": "string"
+                }
+            }
+        },
+        "models.ParseHealth": {
+            "type": "object",
+            "properties": {
+                "canary_url": {
+                    "description": "探测使用的分享链接",
+                    "type": "string"
+                },
+                "consecutive_failures": {
+                    "description": "连续失败次数",
+                    "type": "integer"
+                },
+                "error": {
+                    "description": "最近一次探测失败原因",
+                    "type": "string"
+                },
+                "last_checked_at": {
+                    "description": "最近一次探测时间",
+                    "type": "string"
+                },
+                "last_success_at": {
+                    "description": "最近一次成功时间",
+                    "type": "string"
+                },
+                "latency_ms": {
+                    "description": "最近一次探测耗时",
+                    "type": "integer"
+                },
+                "source": {
+                    "type": "string"
+                },
+                "status": {
+                    "$ref": "#/definitions/models.ParseHealthStatus"
+                }
+            }
+        },
+        "models.ParseHealthStatus": {
+            "type": "string",
+            "enum": [
+                "unknown",
+                "up",
+                "down"
+            ],
+            "x-enum-comments": {
+                "ParseHealthDown": "最近一次探测失败",
+                "ParseHealthUnknown": "未配置探测链接或尚未探测",
+                "ParseHealthUp": "最近一次探测成功"
+            },
+            "x-enum-varnames": [
+                "ParseHealthUnknown",
+                "ParseHealthUp",
+                "ParseHealthDown"
+            ]
+        },
+        "models.ParseStatus": {
+            "type": "object",
+            "properties": {
+                "breakers": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.CircuitBreaker"
+                    }
+                },
+                "health": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ParseHealth"
+                    }
+                },
+                "platforms": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.PlatformInfo"
+                    }
+                }
+            }
+        },
+        "models.PlatformInfo": {
+            "type": "object",
+            "properties": {
+                "domains": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "id_parser": {
+                    "description": "是否支持按视频id解析",
+                    "type": "boolean"
+                },
+                "source": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Step": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "desc": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "order": {
+                    "type": "integer"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Tool": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
                 "description": {
                     "type": "string"
                 },
@@ -317,6 +1988,222 @@ const docTemplate = `{
                     "type": "string"
                 }
             }
+        },
+        "models.VideoParseInfo": {
+            "type": "object",
+            "properties": {
+                "audio_url": {
+                    "description": "音频地址, 仅音视频分离(DASH)的平台返回, 需要与 VideoUrl 合并后播放",
+                    "type": "string"
+                },
+                "author": {
+                    "type": "object",
+                    "properties": {
+                        "avatar": {
+                            "description": "作者头像",
+                            "type": "string"
+                        },
+                        "name": {
+                            "description": "作者名称",
+                            "type": "string"
+                        },
+                        "uid": {
+                            "description": "作者id",
+                            "type": "string"
+                        }
+                    }
+                },
+                "cover_url": {
+                    "description": "视频封面地址",
+                    "type": "string"
+                },
+                "images": {
+                    "description": "图集图片地址列表",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "live_photos": {
+                    "description": "实况图片的视频片段, 没有实况图片时为空",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.LivePhoto"
+                    }
+                },
+                "music_url": {
+                    "description": "音乐播放地址",
+                    "type": "string"
+                },
+                "parts": {
+                    "description": "多P视频的分P列表, 单P视频为空",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.VideoPart"
+                    }
+                },
+                "title": {
+                    "description": "描述",
+                    "type": "string"
+                },
+                "video_url": {
+                    "description": "视频播放地址",
+                    "type": "string"
+                }
+            }
+        },
+        "models.VideoPart": {
+            "type": "object",
+            "properties": {
+                "audio_url": {
+                    "description": "音频流地址, 同 VideoUrl",
+                    "type": "string"
+                },
+                "duration": {
+                    "description": "时长(秒)",
+                    "type": "integer"
+                },
+                "page": {
+                    "description": "分P序号, 从1开始",
+                    "type": "integer"
+                },
+                "title": {
+                    "description": "分P标题",
+                    "type": "string"
+                },
+                "video_url": {
+                    "description": "视频流地址, 只有请求的分P返回, 其他分P通过带 p 参数的分享链接获取",
+                    "type": "string"
+                }
+            }
+        },
+        "response.KeyData": {
+            "type": "object",
+            "properties": {
+                "key": {
+                    "type": "string",
+                    "example": "20240101/120000-result.pdf"
+                }
+            }
+        },
+        "response.KeysData": {
+            "type": "object",
+            "properties": {
+                "keys": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "response.Response": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "description": "错误码, 仅失败时返回",
+                    "type": "string",
+                    "example": "invalid_link"
+                },
+                "data": {},
+                "message": {
+                    "type": "string",
+                    "example": "success"
+                },
+                "request_id": {
+                    "description": "请求ID, 仅失败时返回, 用于排查日志",
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "success"
+                }
+            }
+        },
+        "settings.Flag": {
+            "type": "object",
+            "properties": {
+                "enabled": {
+                    "description": "总开关, 不设置时为开启",
+                    "type": "boolean"
+                },
+                "platforms": {
+                    "description": "值为false的平台关闭, 未配置的平台沿用总开关",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "boolean"
+                    }
+                },
+                "rollout": {
+                    "description": "开启的请求方比例, 0-100, 不设置时为100",
+                    "type": "integer"
+                }
+            }
+        },
+        "settings.Settings": {
+            "type": "object",
+            "properties": {
+                "flags": {
+                    "description": "Flags 功能开关, 支持按平台关闭和按请求方灰度, 未配置的功能默认开启",
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/settings.Flag"
+                    }
+                },
+                "proxy_allowed_hosts": {
+                    "description": "ProxyAllowedHosts 媒体代理允许访问的域名, 包含其子域名, 为空时只允许已支持平台的域名",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "rate_limit": {
+                    "description": "RateLimit 每个客户端IP每分钟允许的请求数, 0 表示不限制",
+                    "type": "integer"
+                },
+                "transcode_profiles": {
+                    "description": "TranscodeProfiles 视频转码参数, 与默认的 default 合并",
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/settings.TranscodeProfile"
+                    }
+                },
+                "user_agents": {
+                    "description": "UserAgents 各平台解析时使用的User-Agent, 多个时随机选择, 未配置的平台使用内置UA",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "settings.TranscodeProfile": {
+            "type": "object",
+            "properties": {
+                "crf": {
+                    "description": "画质, 0-51, 越小画质越好",
+                    "type": "integer"
+                },
+                "max_height": {
+                    "description": "超过该高度时等比缩小, 0 表示不缩放",
+                    "type": "integer"
+                },
+                "preset": {
+                    "description": "libx264 编码速度",
+                    "type": "string"
+                }
+            }
+        }
+    },
+    "securityDefinitions": {
+        "AdminToken": {
+            "description": "管理接口令牌, 格式为 Bearer 令牌",
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
         }
     }
 }`
@@ -0,0 +1,24 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"strings"
+
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/response"
+	"github.com/gofiber/fiber/v2"
+)
+
+// AdminAuth 校验管理接口的访问令牌, 请求头为 Authorization: Bearer 令牌; token 为空时关闭管理接口
+func AdminAuth(token string) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		if token == "" {
+			return response.Fail(ctx, fiber.StatusNotFound, "Not Found")
+		}
+		auth := ctx.Get(fiber.HeaderAuthorization)
+		bearer, ok := strings.CutPrefix(auth, "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(bearer), []byte(token)) != 1 {
+			return response.Fail(ctx, fiber.StatusUnauthorized, "Unauthorized")
+		}
+		return ctx.Next()
+	}
+}
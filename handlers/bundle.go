@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/logging"
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/models"
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/response"
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/service"
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/upstream"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/redis/go-redis/v9"
+)
+
+// parseCacheKeyPrefix 解析结果在Redis中的key前缀
+const parseCacheKeyPrefix = "parse:"
+
+// bundleEntry ZIP包中的单个文件
+type bundleEntry struct {
+	name  string // 不含扩展名的文件名, 扩展名根据响应的Content-Type确定
+	url   string
+	image bool // 是否为图片, 图片下载失败时尝试备用地址
+}
+
+// MediaBundle godoc
+// @Summary 打包下载图集
+// @Description 将解析结果中的图集图片(含实况视频片段)、封面以及可选的背景音乐边下载边打包为ZIP返回, 通过解析接口返回的cache_key或分享链接指定内容
+// @Tags tools
+// @Produce application/zip
+// @Param key query string false "解析接口返回的cache_key"
+// @Param url query string false "分享链接, 未提供key时实时解析"
+// @Param music query bool false "是否包含背景音乐"
+// @Success 200 {file} binary "ZIP文件"
+// @Failure 400 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Failure 503 {object} response.Response
+// @Router /tools/media-bundle [get]
+func (h *CommonHandler) MediaBundle(ctx *fiber.Ctx) error {
+	key := ctx.Query("key")
+	shareURL := ctx.Query("url")
+	withMusic := ctx.QueryBool("music")
+
+	var parseInfo *models.VideoParseInfo
+	var err error
+	switch {
+	case key != "":
+		parseInfo, err = h.loadParseResult(ctx.UserContext(), key)
+		if errors.Is(err, redis.Nil) {
+			return response.Fail(ctx, fiber.StatusNotFound, "Parse result expired, please parse again")
+		}
+		if err != nil {
+			requestLog(ctx).Errorf("load parse result: %v", err)
+			return response.Fail(ctx, fiber.StatusInternalServerError, "Load parse result failed")
+		}
+	case shareURL != "":
+		parseInfo, err = service.ParseVideoShareUrlByRegexp(ctx.UserContext(), shareURL)
+		if err != nil {
+			requestLog(ctx).Errorf("fail parse %v", err)
+			return err
+		}
+	default:
+		return response.Fail(ctx, fiber.StatusBadRequest, "key or url is required")
+	}
+
+	entries := bundleEntries(parseInfo, withMusic)
+	if len(entries) == 0 {
+		return response.Fail(ctx, fiber.StatusNotFound, "No media to bundle")
+	}
+
+	ctx.Set("Content-Type", "application/zip")
+	ctx.Set("Content-Disposition", `attachment; filename="media-bundle.zip"`)
+	ctx.Set("Access-Control-Allow-Origin", "*")
+	ctx.Set("Cache-Control", "no-store")
+
+	// 边下载边写入ZIP, 不在内存中缓存整个压缩包
+	// 写入在handler返回后进行, 沿用请求的调用链
+	spanCtx := ctx.UserContext()
+	ctx.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		writeMediaBundle(spanCtx, w, h.client, entries, maxFileBytes(h.config))
+	})
+	return nil
+}
+
+// bundleEntries 根据解析结果生成需要打包的文件列表
+func bundleEntries(parseInfo *models.VideoParseInfo, withMusic bool) []bundleEntry {
+	entries := make([]bundleEntry, 0, len(parseInfo.Images)+2)
+	for i, image := range parseInfo.Images {
+		entries = append(entries, bundleEntry{name: fmt.Sprintf("images/%02d", i+1), url: image, image: true})
+	}
+	// 实况图片的视频片段以对应图片的序号加 -live 命名, 如 images/01-live.mp4
+	for _, livePhoto := range parseInfo.LivePhotos {
+		entries = append(entries, bundleEntry{name: fmt.Sprintf("images/%02d-live", livePhoto.Index+1), url: livePhoto.VideoUrl})
+	}
+	if parseInfo.CoverUrl != "" {
+		entries = append(entries, bundleEntry{name: "cover", url: parseInfo.CoverUrl, image: true})
+	}
+	if withMusic && parseInfo.MusicUrl != "" {
+		entries = append(entries, bundleEntry{name: "music", url: parseInfo.MusicUrl})
+	}
+	return entries
+}
+
+// writeMediaBundle 依次下载文件并写入ZIP, 单个文件失败或超过 limit 字节时跳过并在 errors.txt 中记录原因
+func writeMediaBundle(ctx context.Context, w *bufio.Writer, client *upstream.Client, entries []bundleEntry, limit int64) {
+	zw := zip.NewWriter(w)
+	var failures []string
+	for _, entry := range entries {
+		if err := writeBundleEntry(ctx, zw, client, entry, limit); err != nil {
+			log.WithContext(ctx).Errorw("打包文件失败", "name", entry.name, "url", logging.RedactURL(entry.url), "error", err)
+			failures = append(failures, fmt.Sprintf("%s: %v", entry.name, err))
+		}
+		// 每个文件写完后立即发送给客户端
+		if err := w.Flush(); err != nil {
+			log.WithContext(ctx).Errorf("发送ZIP数据失败: %v", err)
+			return
+		}
+	}
+	if len(failures) > 0 {
+		if fw, err := zw.Create("errors.txt"); err == nil {
+			io.WriteString(fw, strings.Join(failures, "\n")+"\n")
+		}
+	}
+	if err := zw.Close(); err != nil {
+		log.WithContext(ctx).Errorf("写入ZIP失败: %v", err)
+		return
+	}
+	w.Flush()
+}
+
+// writeBundleEntry 下载单个文件并写入ZIP, 完整读取后再写入, 失败时不会留下不完整的文件
+func writeBundleEntry(ctx context.Context, zw *zip.Writer, client *upstream.Client, entry bundleEntry, limit int64) error {
+	// ctx 沿用请求的调用链, 流式发送时客户端断开不会取消下载, 单个文件的下载时长由出站客户端的超时控制
+	var resp *http.Response
+	var err error
+	if entry.image {
+		resp, _, err = fetchImage(ctx, client, entry.url, nil)
+	} else {
+		// 实况视频片段和音乐没有图片的备用地址
+		resp, _, err = client.GetFirst(ctx, []string{entry.url}, nil)
+		if err != nil {
+			err = service.UpstreamRequestError("获取文件", err)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := readLimited(resp.Body, limit)
+	if err != nil {
+		return err
+	}
+
+	// 图片和音频已经是压缩格式, 直接存储不再压缩
+	fw, err := zw.CreateHeader(&zip.FileHeader{
+		Name:     entry.name + mediaExtension(resp.Header.Get("Content-Type")),
+		Method:   zip.Store,
+		Modified: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = fw.Write(data)
+	return err
+}
+
+// mediaExtension 根据Content-Type返回文件扩展名
+func mediaExtension(contentType string) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "image/jpeg":
+		return ".jpg"
+	case "image/png":
+		return ".png"
+	case "image/webp":
+		return ".webp"
+	case "image/gif":
+		return ".gif"
+	case "audio/mpeg":
+		return ".mp3"
+	case "audio/mp4", "audio/x-m4a":
+		return ".m4a"
+	case "video/mp4":
+		return ".mp4"
+	}
+	if exts, _ := mime.ExtensionsByType(mediaType); len(exts) > 0 {
+		return exts[0]
+	}
+	return ""
+}
+
+// cacheParseResult 缓存解析结果, 返回可用于打包下载的key
+func (h *CommonHandler) cacheParseResult(ctx context.Context, shareURL string, parseInfo *models.VideoParseInfo) (string, error) {
+	data, err := json.Marshal(parseInfo)
+	if err != nil {
+		return "", err
+	}
+	key := hashKey(shareURL)[:16]
+	if err := h.redis.Set(ctx, parseCacheKeyPrefix+key, data, h.config.MediaConfig.ParseCacheTTL).Err(); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// loadParseResult 读取缓存的解析结果, 不存在时返回 redis.Nil
+func (h *CommonHandler) loadParseResult(ctx context.Context, key string) (*models.VideoParseInfo, error) {
+	data, err := h.redis.Get(ctx, parseCacheKeyPrefix+key).Bytes()
+	if err != nil {
+		return nil, err
+	}
+	parseInfo := &models.VideoParseInfo{}
+	if err := json.Unmarshal(data, parseInfo); err != nil {
+		return nil, err
+	}
+	return parseInfo, nil
+}
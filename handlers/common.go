@@ -2,18 +2,29 @@ package handlers
 
 import (
 	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"net/http"
+	neturl "net/url"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/config"
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/mediacache"
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/metrics"
 	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/models"
 	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/repositories"
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/response"
 	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/service"
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/settings"
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/upstream"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/log"
 	"github.com/redis/go-redis/v9"
@@ -21,10 +32,26 @@ import (
 )
 
 type CommonHandler struct {
-	redis      *redis.Client
-	cos        *cos.Client
-	repository *repositories.ToolRepository
-	config     *config.EnvConfig
+	redis            redis.UniversalClient
+	cos              *cos.Client
+	repository       *repositories.ToolRepository
+	config           *config.EnvConfig
+	client           *upstream.Client                   // 获取远程媒体的出站客户端
+	watermarkFilters map[string]service.WatermarkFilter // 各平台去水印过滤器
+	settings         *settings.Manager                  // 运行时配置
+	mediaCache       *mediacache.Cache                  // 媒体代理缓存
+}
+
+// UploadData 文件上传结果
+type UploadData struct {
+	Url  string `json:"url"`  // 文件key
+	Name string `json:"name"` // 原始文件名
+}
+
+// ParseShareUrlResponse 链接解析响应, cache_key 可用于图集打包下载
+type ParseShareUrlResponse struct {
+	response.Response
+	CacheKey string `json:"cache_key"`
 }
 
 // GetTools godoc
@@ -33,29 +60,19 @@ type CommonHandler struct {
 // @Tags tools
 // @Accept json
 // @Produce json
-// @Success 200 {object} map[string]interface{}
-// @Failure 404 {object} map[string]interface{}
-// @Failure 500 {object} map[string]interface{}
+// @Success 200 {object} response.Response{data=[]models.Tool}
+// @Failure 404 {object} response.Response
+// @Failure 500 {object} response.Response
 // @Router /tools/list [get]
 func (h *CommonHandler) GetTools(ctx *fiber.Ctx) error {
-	tools, err := h.repository.GetAllTools()
+	tools, err := h.repository.GetAllTools(ctx.UserContext())
 	if err != nil {
-		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"status":  "error",
-			"message": "Get tools list failed",
-		})
+		return response.Fail(ctx, fiber.StatusInternalServerError, "Get tools list failed")
 	}
 	if len(tools) == 0 {
-		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"status":  "error",
-			"message": "No tools found",
-		})
-	}
-	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
-		"status":  "success",
-		"message": "Get tools list success",
-		"data":    tools,
-	})
+		return response.Fail(ctx, fiber.StatusNotFound, "No tools found")
+	}
+	return response.Success(ctx, "Get tools list success", tools)
 }
 
 // CreateTool godoc
@@ -65,113 +82,106 @@ func (h *CommonHandler) GetTools(ctx *fiber.Ctx) error {
 // @Accept json
 // @Produce json
 // @Param tool body models.Tool true "工具信息"
-// @Success 200 {object} map[string]interface{}
-// @Failure 400 {object} map[string]interface{}
-// @Failure 500 {object} map[string]interface{}
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Failure 500 {object} response.Response
 // @Router /tools [post]
 func (h *CommonHandler) CreateTool(ctx *fiber.Ctx) error {
 	tool := &models.Tool{}
 	if err := ctx.BodyParser(tool); err != nil {
-		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"status":  "fail",
-			"message": "Invalid request body",
-		})
-	}
-	if err := h.repository.CreateTool(tool); err != nil {
-		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"status":  "fail",
-			"message": "Create tool failed",
-		})
-	}
-	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
-		"status":  "success",
-		"message": "Create tool success",
-	})
+		return response.Fail(ctx, fiber.StatusBadRequest, "Invalid request body")
+	}
+	if err := h.repository.CreateTool(ctx.UserContext(), tool); err != nil {
+		return response.Fail(ctx, fiber.StatusInternalServerError, "Create tool failed")
+	}
+	return response.Success(ctx, "Create tool success", nil)
 }
 
 // Upload godoc
 // @Summary 上传文件
-// @Description 上传文件到服务器
+// @Description 上传文件到服务器, 支持pdf/docx/xlsx/pptx
 // @Tags file
 // @Accept multipart/form-data
 // @Produce json
 // @Param file formData file true "要上传的文件"
-// @Success 200 {object} map[string]interface{}
-// @Failure 400 {object} map[string]interface{}
-// @Failure 500 {object} map[string]interface{}
+// @Success 200 {object} response.Response{data=handlers.UploadData}
+// @Failure 400 {object} response.Response
+// @Failure 500 {object} response.Response
 // @Router /file/upload [post]
 func (h *CommonHandler) Upload(ctx *fiber.Ctx) error {
 	file, err := ctx.FormFile("file")
 	if err != nil {
-		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"status":  "fail",
-			"message": "Invalid file",
-		})
-	}
-	fileType := strings.Split(file.Filename, ".")[1]
-	if fileType != "pdf" {
-		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"status":  "fail",
-			"message": "Invalid file type Please upload a pdf file",
-		})
+		return response.Fail(ctx, fiber.StatusBadRequest, "Invalid file")
+	}
+	fileType := strings.ToLower(filepath.Ext(file.Filename))
+	if fileType != ".pdf" && !service.OfficeExtensions[fileType] {
+		return response.Fail(ctx, fiber.StatusBadRequest, "Invalid file type Please upload a pdf, docx, xlsx or pptx file")
 	}
 	open, err := file.Open()
 	if err != nil {
-		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"status":  "fail",
-			"message": "Open file failed",
-		})
+		return response.Fail(ctx, fiber.StatusInternalServerError, "Open file failed")
 	}
-	fileUrl := time.Now().Format("20060102") + "/" + time.Now().Format("150405-") + file.Filename
-	_, err = h.cos.Object.Put(ctx.Context(), fileUrl, open, nil)
-	if err != nil {
-		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"status":  "fail",
-			"message": "Upload file failed",
-		})
-	}
-	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
-		"status":  "success",
-		"message": "Upload file success",
-		"data": map[string]string{
-			"url":  fileUrl,
-			"name": file.Filename,
-		},
+	defer open.Close()
+	fileUrl := newObjectKey(file.Filename)
+	if err := putObject(ctx.UserContext(), h.cos, fileUrl, open); err != nil {
+		return response.Fail(ctx, fiber.StatusInternalServerError, "Upload file failed")
+	}
+	return response.Success(ctx, "Upload file success", UploadData{
+		Url:  fileUrl,
+		Name: file.Filename,
 	})
 }
 
+// ParseShareUrlRequest 链接解析请求
+type ParseShareUrlRequest struct {
+	URL string `json:"url" example:"https://v.douyin.com/iFRvNakm/"` // 分享链接或包含链接的分享文案
+}
+
+// ParseShareUrl godoc
+// @Summary 解析视频分享链接
+// @Description 解析各平台分享链接或分享文案, 返回视频/图集地址、封面和作者信息
+// @Tags tools
+// @Accept json
+// @Produce json
+// @Param request body ParseShareUrlRequest true "分享链接"
+// @Success 200 {object} handlers.ParseShareUrlResponse{data=models.VideoParseInfo}
+// @Failure 400 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 422 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Failure 502 {object} response.Response
+// @Failure 503 {object} response.Response
+// @Failure 504 {object} response.Response
+// @Router /tools/parse [post]
 func (h *CommonHandler) ParseShareUrl(ctx *fiber.Ctx) error {
 	// 从JSON请求体中获取URL
-	var req struct {
-		URL string `json:"url"`
-	}
+	var req ParseShareUrlRequest
 
 	if err := ctx.BodyParser(&req); err != nil {
-		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"status":  "fail",
-			"message": "Invalid request format",
-		})
+		return response.Fail(ctx, fiber.StatusBadRequest, "Invalid request format")
 	}
 
 	if req.URL == "" {
-		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"status":  "fail",
-			"message": "URL is required",
-		})
+		return response.Fail(ctx, fiber.StatusBadRequest, "URL is required")
 	}
 
-	parseInfo, err := service.ParseVideoShareUrlByRegexp(req.URL)
+	parseInfo, err := service.ParseVideoShareUrlByRegexp(ctx.UserContext(), req.URL)
 	if err != nil {
-		log.Errorf("fail parse %v", err)
-		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"status":  "fail",
-			"message": "Parse URL fail",
-		})
-	}
-	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
-		"status":  "success",
-		"message": "Parse URL success",
-		"data":    parseInfo,
+		requestLog(ctx).Errorf("fail parse %v", err)
+		return err
+	}
+	// 缓存解析结果, 供图集打包下载使用; 缓存失败不影响解析结果返回
+	cacheKey, err := h.cacheParseResult(ctx.UserContext(), req.URL, parseInfo)
+	if err != nil {
+		requestLog(ctx).Warnf("cache parse result: %v", err)
+	}
+	return ctx.Status(fiber.StatusOK).JSON(ParseShareUrlResponse{
+		Response: response.Response{
+			Status:  response.StatusSuccess,
+			Message: "Parse URL success",
+			Data:    parseInfo,
+		},
+		CacheKey: cacheKey,
 	})
 }
 
@@ -183,304 +193,424 @@ func (h *CommonHandler) ParseShareUrl(ctx *fiber.Ctx) error {
 // @Produce octet-stream
 // @Param url query string true "媒体文件URL"
 // @Param type query string false "媒体类型(video/image)"
-// @Param format query string false "输出格式(mp4/webm/mov)"
+// @Param format query string false "输出格式(视频: mp4/webm/mov; 图片: jpeg/png/webp)"
+// @Param w query int false "图片目标宽度"
+// @Param h query int false "图片目标高度"
+// @Param fit query string false "图片缩放模式(contain/cover/fill)"
+// @Param crop query string false "图片裁剪区域(x,y,w,h)"
+// @Param q query int false "图片压缩质量(1-100)"
+// @Param clean query bool false "按平台配置去除水印"
+// @Param fallback query []string false "备用地址, 原地址失败时依次尝试, 可重复" collectionFormat(multi)
+// @Param If-None-Match header string false "上次响应的ETag, 未变化时返回304"
+// @Param If-Modified-Since header string false "上次响应的Last-Modified, 未变化时返回304"
 // @Success 200 {file} binary "媒体文件"
-// @Failure 400 {object} map[string]interface{}
-// @Failure 405 {object} map[string]interface{}
-// @Failure 500 {object} map[string]interface{}
+// @Header 200 {string} X-Cache "内容来源: memory、disk、cos 或 miss(回源)"
+// @Header 200 {string} ETag "原样返回时为源站ETag, 处理后的内容为内容摘要"
+// @Header 200 {string} Last-Modified "原样返回时为源站的修改时间"
+// @Success 304 "内容未变化"
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 405 {object} response.Response
+// @Failure 422 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Failure 502 {object} response.Response
+// @Failure 503 {object} response.Response
+// @Failure 504 {object} response.Response
 // @Router /tools/media-proxy [get]
 func (h *CommonHandler) ProxyMedia(ctx *fiber.Ctx) error {
 	// 获取请求信息
 	mediaURL := ctx.Query("url")
 	mediaType := ctx.Query("type", "video")
 	format := ctx.Query("format", "")
-	userAgent := ctx.Get("User-Agent")
+	clean := ctx.QueryBool("clean")
 
-	// 记录请求信息
-	log.Infof("媒体代理请求: URL=%s, 类型=%s, 格式=%s, UA=%s", mediaURL, mediaType, format, userAgent)
+	// 记录请求信息, 媒体地址可能带有签名参数, 只记录平台和域名
+	requestLog(ctx).Infow("媒体代理请求",
+		"platform", service.MediaPlatform(mediaURL),
+		"upstream_host", upstreamHost(mediaURL),
+		"type", mediaType,
+		"format", format,
+		"clean", clean,
+	)
 
 	// 只允许GET请求
 	if ctx.Method() != "GET" {
-		return ctx.Status(fiber.StatusMethodNotAllowed).JSON(fiber.Map{
-			"error": "Method Not Allowed",
-		})
+		return response.Fail(ctx, fiber.StatusMethodNotAllowed, "Method Not Allowed")
 	}
 
 	// 验证URL参数
 	if mediaURL == "" {
-		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "缺少url参数",
-		})
+		return response.Fail(ctx, fiber.StatusBadRequest, "缺少url参数")
 	}
 
 	// 验证URL格式和协议
 	if !strings.HasPrefix(mediaURL, "http://") && !strings.HasPrefix(mediaURL, "https://") {
-		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "无效的URL协议",
-		})
+		return response.Fail(ctx, fiber.StatusBadRequest, "无效的URL协议")
+	}
+	runtime := h.settings.Get()
+	if !allowedMediaURL(runtime, mediaURL) {
+		return response.Fail(ctx, fiber.StatusForbidden, "不允许代理该域名")
 	}
 
-	// 创建HTTP客户端
-	client := &http.Client{
-		Timeout: 30 * time.Second,
-		CheckRedirect: func(req *http.Request, via []*http.Request) error {
-			if len(via) >= 10 {
-				return fmt.Errorf("重定向次数过多")
-			}
-			return nil
-		},
+	// 原地址失败时依次尝试的备用地址, 如解析结果中的其他CDN地址
+	var fallbacks []string
+	for _, fallback := range ctx.Context().QueryArgs().PeekMulti("fallback") {
+		if allowedMediaURL(runtime, string(fallback)) {
+			fallbacks = append(fallbacks, string(fallback))
+		}
 	}
 
 	// 根据媒体类型处理请求
 	switch mediaType {
 	case "video":
-		return h.handleVideoProxy(ctx, client, mediaURL, format)
+		return h.handleVideoProxy(ctx, mediaURL, fallbacks, format, clean)
 	case "image":
-		return h.handleImageProxy(ctx, client, mediaURL)
+		opts := service.ImageOptions{}
+		if err := ctx.QueryParser(&opts); err != nil {
+			return response.Fail(ctx, fiber.StatusBadRequest, "无效的图片处理参数")
+		}
+		return h.handleImageProxy(ctx, mediaURL, fallbacks, opts, clean)
 	default:
-		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "不支持的媒体类型",
-		})
+		return response.Fail(ctx, fiber.StatusBadRequest, "不支持的媒体类型")
 	}
 }
 
-// handleVideoProxy 处理视频代理请求
-func (h *CommonHandler) handleVideoProxy(ctx *fiber.Ctx, client *http.Client, url string, format string) error {
-	// 创建请求
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		log.Errorf("创建请求失败: %v", err)
-		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": fmt.Sprintf("创建请求失败: %v", err),
-		})
+// handleVideoProxy 处理视频代理请求, 原始或转码后的视频按地址和参数缓存
+func (h *CommonHandler) handleVideoProxy(ctx *fiber.Ctx, url string, fallbacks []string, format string, clean bool) error {
+	// 需要去水印时使用平台对应的过滤器
+	var videoFilter string
+	if filter, ok := h.watermarkFilter(url, clean); ok {
+		videoFilter = filter.String()
 	}
+	profile, _ := h.settings.Get().TranscodeProfile(settings.DefaultTranscodeProfile)
 
-	// 添加用户代理头
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
+	key := mediaKey(url, fallbacks, "video", format, videoFilter, fmt.Sprintf("%+v", profile))
+	entry, level, err := h.mediaCache.Get(ctx.UserContext(), key, func(fetchCtx context.Context) (*mediacache.Entry, error) {
+		return h.fetchVideo(fetchCtx, url, fallbacks, format, videoFilter, profile)
+	})
+	if err != nil {
+		return err
+	}
+	ctx.Set("Accept-Ranges", "bytes")
+	return sendMedia(ctx, entry, level)
+}
 
-	// 发送请求
-	resp, err := client.Do(req)
+// fetchVideo 获取视频, 需要时去水印或转换为MP4
+func (h *CommonHandler) fetchVideo(ctx context.Context, url string, fallbacks []string, format, videoFilter string, profile settings.TranscodeProfile) (*mediacache.Entry, error) {
+	// User-Agent、Referer 等请求头由出站客户端按域名策略设置
+	resp, actualURL, err := h.client.GetFirst(ctx, append([]string{url}, fallbacks...), nil)
 	if err != nil {
-		log.Errorf("获取视频失败: %v", err)
-		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": fmt.Sprintf("获取视频失败: %v", err),
-		})
+		log.WithContext(ctx).Errorf("获取视频失败: %v", err)
+		return nil, service.UpstreamRequestError("获取视频", err)
 	}
 	defer resp.Body.Close()
-
-	// 检查状态码
-	if resp.StatusCode != http.StatusOK {
-		log.Errorf("源服务器响应错误: %s", resp.Status)
-		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": fmt.Sprintf("源服务器响应错误: %s", resp.Status),
-		})
+	header := map[string]string{}
+	if actualURL != url {
+		header["X-Original-URL"] = url
+		header["X-Actual-URL"] = actualURL
 	}
 
 	// 读取响应内容
-	videoData, err := io.ReadAll(resp.Body)
+	videoData, err := readLimited(resp.Body, maxFileBytes(h.config))
 	if err != nil {
-		log.Errorf("读取视频数据失败: %v", err)
-		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": fmt.Sprintf("读取视频数据失败: %v", err),
-		})
+		log.WithContext(ctx).Errorf("读取视频数据失败: %v", err)
+		return nil, err
 	}
 
 	// 检查视频数据的有效性
 	if len(videoData) < 1024 {
-		log.Errorf("视频数据无效或太小: %d bytes", len(videoData))
-		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "视频数据无效或太小",
-		})
+		log.WithContext(ctx).Errorf("视频数据无效或太小: %d bytes", len(videoData))
+		return nil, fmt.Errorf("%w: 视频数据无效或太小", service.ErrContentUnavailable)
+	}
+
+	if videoFilter != "" {
+		header["X-Watermark-Removed"] = "true"
 	}
 
-	// 检测视频格式，如果需要且不是MP4，则转换为MP4
-	needConversion := format == "mp4" || (format == "" && !isMP4(videoData, resp.Header.Get("Content-Type")))
+	// 检测视频格式，如果需要且不是MP4，则转换为MP4；去水印同样需要重新编码
+	needConversion := videoFilter != "" || format == "mp4" || (format == "" && !isMP4(videoData, resp.Header.Get("Content-Type")))
 
 	if needConversion {
 		// 使用FFmpeg进行格式转换
-		convertedData, err := convertToMP4(videoData)
+		convertedData, err := convertToMP4(ctx, h.config.ToolConfig.FfmpegPath, videoData, videoFilter, profile)
 		if err != nil {
-			log.Errorf("视频格式转换失败: %v", err)
-			return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": fmt.Sprintf("视频格式转换失败: %v", err),
-			})
+			log.WithContext(ctx).Errorf("视频格式转换失败: %v", err)
+			return nil, fmt.Errorf("%w: %v", service.ErrMediaProcessing, err)
 		}
 		videoData = convertedData
 
 		// 设置正确的Content-Type和文件扩展名
-		ctx.Set("Content-Type", "video/mp4")
-		ctx.Set("Content-Disposition", `attachment; filename="video.mp4"`)
+		header["Content-Type"] = "video/mp4"
+		header["Content-Disposition"] = `attachment; filename="video.mp4"`
+		header[fiber.HeaderETag] = contentETag(videoData)
 	} else {
 		// 保持原始格式
-		ctx.Set("Content-Type", resp.Header.Get("Content-Type"))
+		header["Content-Type"] = resp.Header.Get("Content-Type")
+		setUpstreamValidators(header, resp.Header, videoData)
 	}
 
-	// 设置其他响应头
-	ctx.Set("X-Content-Type-Options", "nosniff")
-	ctx.Set("Accept-Ranges", "bytes")
-	ctx.Set("Content-Length", fmt.Sprintf("%d", len(videoData)))
-	ctx.Set("Access-Control-Allow-Origin", "*")
-	ctx.Set("Cache-Control", "public, max-age=3600") // 缓存1小时
+	return &mediacache.Entry{Header: header, Data: videoData}, nil
+}
+
+// handleImageProxy 处理图片代理请求, 指定了处理参数或需要去水印时返回处理后的图片
+func (h *CommonHandler) handleImageProxy(ctx *fiber.Ctx, url string, fallbacks []string, opts service.ImageOptions, clean bool) error {
+	filter, hasFilter := h.watermarkFilter(url, clean)
+	if hasFilter {
+		return h.handleImageTransform(ctx, url, fallbacks, opts, &filter)
+	}
+	if !opts.IsZero() {
+		return h.handleImageTransform(ctx, url, fallbacks, opts, nil)
+	}
 
-	// 返回视频数据
-	return ctx.Send(videoData)
+	entry, level, err := h.mediaCache.Get(ctx.UserContext(), mediaKey(url, fallbacks, "image"), func(fetchCtx context.Context) (*mediacache.Entry, error) {
+		resp, actualURL, err := fetchImage(fetchCtx, h.client, url, fallbacks)
+		if err != nil {
+			log.WithContext(fetchCtx).Errorf("获取图片失败: %v", err)
+			return nil, err
+		}
+		defer resp.Body.Close()
+		data, err := readLimited(resp.Body, maxFileBytes(h.config))
+		if err != nil {
+			log.WithContext(fetchCtx).Errorf("读取图片数据失败: %v", err)
+			return nil, err
+		}
+		header := map[string]string{"Content-Type": resp.Header.Get("Content-Type")}
+		setUpstreamValidators(header, resp.Header, data)
+		if actualURL != url {
+			header["X-Original-URL"] = url
+			header["X-Actual-URL"] = actualURL
+		}
+		return &mediacache.Entry{Header: header, Data: data}, nil
+	})
+	if err != nil {
+		return err
+	}
+	return sendMedia(ctx, entry, level)
 }
 
-// handleImageProxy 处理图片代理请求
-func (h *CommonHandler) handleImageProxy(ctx *fiber.Ctx, client *http.Client, url string) error {
-	originalURL := url
-
-	// 特殊处理小红书域名的图片链接
-	if strings.Contains(url, "xhscdn.com") || strings.Contains(url, "xiaohongshu.com") {
-		// 添加备用URL处理逻辑
-		backupURLs := []string{url}
-
-		// 尝试从URL提取图片ID
-		var imgId string
-		if strings.Contains(url, "!") {
-			// 处理形如 xxx/yyy!format 的URL
-			urlPart := url[strings.LastIndex(url, "/")+1:]
-			if idx := strings.Index(urlPart, "!"); idx > 0 {
-				imgId = urlPart[:idx]
+// handleImageTransform 获取图片并按参数去水印、缩放、裁剪、转换格式, 处理结果按地址和参数缓存
+func (h *CommonHandler) handleImageTransform(ctx *fiber.Ctx, url string, fallbacks []string, opts service.ImageOptions, filter *service.WatermarkFilter) error {
+	if err := opts.Normalize(); err != nil {
+		return response.Fail(ctx, fiber.StatusBadRequest, err.Error())
+	}
+
+	params := []string{"image", opts.CacheKey()}
+	if filter != nil {
+		params = append(params, filter.String())
+	}
+	entry, level, err := h.mediaCache.Get(ctx.UserContext(), mediaKey(url, fallbacks, params...), func(fetchCtx context.Context) (*mediacache.Entry, error) {
+		resp, _, err := fetchImage(fetchCtx, h.client, url, fallbacks)
+		if err != nil {
+			log.WithContext(fetchCtx).Errorf("获取图片失败: %v", err)
+			return nil, err
+		}
+		source, err := readLimited(resp.Body, maxFileBytes(h.config))
+		resp.Body.Close()
+		if err != nil {
+			log.WithContext(fetchCtx).Errorf("读取图片数据失败: %v", err)
+			return nil, err
+		}
+
+		header := map[string]string{}
+		if filter != nil {
+			if source, err = service.CleanImage(fetchCtx, h.config.ToolConfig.FfmpegPath, source, *filter); err != nil {
+				log.WithContext(fetchCtx).Errorf("图片去水印失败: %v", err)
+				return nil, fmt.Errorf("%w: %v", service.ErrMediaProcessing, err)
 			}
-		} else if strings.Contains(url, "?") {
-			// 处理带查询参数的URL
-			urlPath := strings.Split(url, "?")[0]
-			imgId = urlPath[strings.LastIndex(urlPath, "/")+1:]
-		} else {
-			// 处理其他格式URL
-			imgId = url[strings.LastIndex(url, "/")+1:]
+			header["X-Watermark-Removed"] = "true"
 		}
 
-		// 如果能提取到ID，则添加备用URL
-		if imgId != "" && len(imgId) > 10 {
-			// 添加ci.xiaohongshu.com备用URL
-			backupURLs = append(backupURLs, fmt.Sprintf("https://ci.xiaohongshu.com/%s?imageView2/2/w/0/format/jpg", imgId))
+		data, contentType, err := service.ProcessImage(fetchCtx, h.config.ToolConfig.FfmpegPath, source, opts)
+		if err != nil {
+			log.WithContext(fetchCtx).Errorf("图片处理失败: %v", err)
+			return nil, fmt.Errorf("%w: %v", service.ErrMediaProcessing, err)
 		}
+		header["Content-Type"] = contentType
+		header[fiber.HeaderETag] = contentETag(data)
+		return &mediacache.Entry{Header: header, Data: data}, nil
+	})
+	if err != nil {
+		return err
+	}
+	return sendMedia(ctx, entry, level)
+}
 
-		// 尝试每个URL，直到成功获取图片
-		for i, tryURL := range backupURLs {
-			log.Infof("尝试获取图片 (尝试 %d/%d): %s", i+1, len(backupURLs), tryURL)
-
-			// 创建请求
-			req, err := http.NewRequest("GET", tryURL, nil)
-			if err != nil {
-				log.Errorf("创建请求失败 (URL: %s): %v", tryURL, err)
-				if i < len(backupURLs)-1 {
-					continue // 尝试下一个URL
-				}
-				return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-					"error": fmt.Sprintf("创建请求失败: %v", err),
-				})
-			}
+// mediaKey 生成代理内容的缓存键; 备用地址由请求方提供且可能取到不同的内容, 排序后一并计入, 避免与只请求原地址的结果共用缓存和合并请求
+func mediaKey(url string, fallbacks []string, params ...string) string {
+	if len(fallbacks) == 0 {
+		return mediacache.Key(url, params...)
+	}
+	sorted := slices.Clone(fallbacks)
+	slices.Sort(sorted)
+	return mediacache.Key(url, append(slices.Clone(params), "fallback="+strings.Join(sorted, ","))...)
+}
 
-			// 添加用户代理头 - 使用更真实的移动设备UA
-			req.Header.Set("User-Agent", "Mozilla/5.0 (iPhone; CPU iPhone OS 16_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.0 Mobile/15E148 Safari/604.1")
-			req.Header.Set("Referer", "https://www.xiaohongshu.com/")
-
-			// 发送请求
-			resp, err := client.Do(req)
-			if err != nil {
-				log.Errorf("获取图片失败 (URL: %s): %v", tryURL, err)
-				if i < len(backupURLs)-1 {
-					continue // 尝试下一个URL
-				}
-				return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-					"error": fmt.Sprintf("获取图片失败: %v", err),
-				})
-			}
+// sendMedia 返回缓存的媒体内容和保存的响应头, X-Cache 为内容来源; 条件请求命中时返回304
+func sendMedia(ctx *fiber.Ctx, entry *mediacache.Entry, level string) error {
+	for key, value := range entry.Header {
+		ctx.Set(key, value)
+	}
+	ctx.Set("X-Cache", level)
+	ctx.Set("X-Content-Type-Options", "nosniff")
+	ctx.Set("Access-Control-Allow-Origin", "*")
+	ctx.Set("Cache-Control", "public, max-age=3600") // 缓存1小时
+	if notModified(ctx, entry.Header[fiber.HeaderETag], entry.Header[fiber.HeaderLastModified]) {
+		return ctx.SendStatus(fiber.StatusNotModified)
+	}
+	return ctx.Send(entry.Data)
+}
 
-			// 检查状态码
-			if resp.StatusCode != http.StatusOK {
-				log.Errorf("源服务器响应错误 (URL: %s): %s", tryURL, resp.Status)
-				resp.Body.Close()
-				if i < len(backupURLs)-1 {
-					continue // 尝试下一个URL
-				}
-				return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-					"error": fmt.Sprintf("源服务器响应错误: %s", resp.Status),
-				})
-			}
+// setUpstreamValidators 原样返回源站内容时沿用源站的 ETag 和 Last-Modified, 源站未返回 ETag 时按内容计算
+func setUpstreamValidators(header map[string]string, upstream http.Header, data []byte) {
+	if etag := upstream.Get(fiber.HeaderETag); etag != "" {
+		header[fiber.HeaderETag] = etag
+	} else {
+		header[fiber.HeaderETag] = contentETag(data)
+	}
+	if lastModified := upstream.Get(fiber.HeaderLastModified); lastModified != "" {
+		header[fiber.HeaderLastModified] = lastModified
+	}
+}
 
-			// 设置响应头
-			ctx.Set("Content-Type", resp.Header.Get("Content-Type"))
-			ctx.Set("X-Content-Type-Options", "nosniff")
-			ctx.Set("Access-Control-Allow-Origin", "*")
-			ctx.Set("Cache-Control", "public, max-age=3600") // 缓存1小时
-			ctx.Set("X-Original-URL", originalURL)
-			ctx.Set("X-Actual-URL", tryURL)
-
-			// 将图片流式传输给客户端
-			_, err = io.Copy(ctx.Response().BodyWriter(), resp.Body)
-			resp.Body.Close()
-			if err != nil {
-				log.Errorf("传输图片数据失败 (URL: %s): %v", tryURL, err)
-				if i < len(backupURLs)-1 {
-					continue // 尝试下一个URL
-				}
-				return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-					"error": fmt.Sprintf("传输图片数据失败: %v", err),
-				})
-			}
+// contentETag 按内容计算ETag, 用于转码、去水印等处理后的内容
+func contentETag(data []byte) string {
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
 
-			// 如果成功获取图片，记录使用的URL
-			log.Infof("成功获取图片: %s (使用URL: %s)", originalURL, tryURL)
-			return nil
+// notModified 按 RFC 9110 判断条件请求能否返回304
+//
+// 有 If-None-Match 时只按弱比较匹配ETag, 忽略 If-Modified-Since; 否则比较 If-Modified-Since 与 Last-Modified
+func notModified(ctx *fiber.Ctx, etag, lastModified string) bool {
+	if noneMatch := ctx.Get(fiber.HeaderIfNoneMatch); noneMatch != "" {
+		if etag == "" {
+			return false
 		}
+		for _, tag := range strings.Split(noneMatch, ",") {
+			tag = strings.TrimSpace(tag)
+			if tag == "*" || strings.TrimPrefix(tag, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		}
+		return false
+	}
+
+	modifiedSince := ctx.Get(fiber.HeaderIfModifiedSince)
+	if modifiedSince == "" || lastModified == "" {
+		return false
+	}
+	since, err := http.ParseTime(modifiedSince)
+	if err != nil {
+		return false
+	}
+	modified, err := http.ParseTime(lastModified)
+	if err != nil {
+		return false
+	}
+	return !modified.After(since)
+}
 
-		// 如果所有URL都失败了
-		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "无法获取图片，所有尝试均失败",
-		})
+// watermarkFilter 返回媒体地址所属平台的去水印过滤器, 未请求去水印或平台未配置时返回false
+func (h *CommonHandler) watermarkFilter(url string, clean bool) (service.WatermarkFilter, bool) {
+	if !clean {
+		return service.WatermarkFilter{}, false
 	}
+	filter, ok := h.watermarkFilters[service.MediaPlatform(url)]
+	return filter, ok
+}
 
-	// 处理其他域名的普通图片请求
-	// 创建请求
-	req, err := http.NewRequest("GET", url, nil)
+// mustWatermarkFilters 解析去水印过滤器配置, 配置错误时终止启动
+func mustWatermarkFilters(config *config.EnvConfig) map[string]service.WatermarkFilter {
+	filters, err := service.ParseWatermarkFilters(config.MediaConfig.WatermarkFilters)
 	if err != nil {
-		log.Errorf("创建请求失败: %v", err)
-		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": fmt.Sprintf("创建请求失败: %v", err),
-		})
+		log.Fatalf("Error parsing WATERMARK_FILTERS: %v", err)
 	}
+	return filters
+}
+
+// requestLog 返回带请求上下文的logger, 日志附带 request_id 和 trace_id
+func requestLog(ctx *fiber.Ctx) log.CommonLogger {
+	return log.WithContext(ctx.UserContext())
+}
 
-	// 添加用户代理头
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
+// allowedMediaURL 判断是否为允许服务端访问的http/https地址, 域名需在运行时配置的 proxy_allowed_hosts 中, 未配置时只允许已支持平台的域名
+func allowedMediaURL(runtime *settings.Settings, mediaURL string) bool {
+	return isHTTPURL(mediaURL) && runtime.AllowsProxyHost(upstreamHost(mediaURL), service.PlatformDomains())
+}
 
-	// 发送请求
-	resp, err := client.Do(req)
+// upstreamHost 返回媒体地址的域名, 用于日志中代替完整地址
+func upstreamHost(mediaURL string) string {
+	u, err := neturl.Parse(mediaURL)
 	if err != nil {
-		log.Errorf("获取图片失败: %v", err)
-		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": fmt.Sprintf("获取图片失败: %v", err),
-		})
+		return ""
 	}
-	defer resp.Body.Close()
+	return u.Hostname()
+}
 
-	// 检查状态码
-	if resp.StatusCode != http.StatusOK {
-		log.Errorf("源服务器响应错误: %s", resp.Status)
-		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": fmt.Sprintf("源服务器响应错误: %s", resp.Status),
-		})
+// maxFileBytes 返回允许读取的最大文件字节数
+func maxFileBytes(config *config.EnvConfig) int64 {
+	if config.MaxFileSize <= 0 {
+		return 50 << 20
 	}
+	return int64(config.MaxFileSize) << 20
+}
 
-	// 设置响应头
-	ctx.Set("Content-Type", resp.Header.Get("Content-Type"))
-	ctx.Set("X-Content-Type-Options", "nosniff")
-	ctx.Set("Access-Control-Allow-Origin", "*")
-	ctx.Set("Cache-Control", "public, max-age=3600") // 缓存1小时
+// imageCandidateURLs 返回获取图片时依次尝试的URL, 小红书图片会附加备用地址
+func imageCandidateURLs(url string) []string {
+	candidates := []string{url}
+	if !isXiaohongshuURL(url) {
+		return candidates
+	}
 
-	// 将图片流式传输给客户端
-	_, err = io.Copy(ctx.Response().BodyWriter(), resp.Body)
+	// 尝试从URL提取图片ID
+	var imgId string
+	if strings.Contains(url, "!") {
+		// 处理形如 xxx/yyy!format 的URL
+		urlPart := url[strings.LastIndex(url, "/")+1:]
+		if idx := strings.Index(urlPart, "!"); idx > 0 {
+			imgId = urlPart[:idx]
+		}
+	} else if strings.Contains(url, "?") {
+		// 处理带查询参数的URL
+		urlPath := strings.Split(url, "?")[0]
+		imgId = urlPath[strings.LastIndex(urlPath, "/")+1:]
+	} else {
+		// 处理其他格式URL
+		imgId = url[strings.LastIndex(url, "/")+1:]
+	}
+
+	// 如果能提取到ID，则添加ci.xiaohongshu.com备用URL
+	if imgId != "" && len(imgId) > 10 {
+		candidates = append(candidates, fmt.Sprintf("https://ci.xiaohongshu.com/%s?imageView2/2/w/0/format/jpg", imgId))
+	}
+	return candidates
+}
+
+// isXiaohongshuURL 判断是否为小红书域名的链接
+func isXiaohongshuURL(url string) bool {
+	return strings.Contains(url, "xhscdn.com") || strings.Contains(url, "xiaohongshu.com")
+}
+
+// fetchImage 依次尝试原地址、小红书备用地址和调用方提供的备用地址获取图片, 返回第一个成功的响应及实际使用的URL
+func fetchImage(ctx context.Context, client *upstream.Client, url string, fallbacks []string) (*http.Response, string, error) {
+	resp, actualURL, err := client.GetFirst(ctx, append(imageCandidateURLs(url), fallbacks...), nil)
 	if err != nil {
-		log.Errorf("传输图片数据失败: %v", err)
-		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": fmt.Sprintf("传输图片数据失败: %v", err),
-		})
+		return nil, "", service.UpstreamRequestError("获取图片", err)
 	}
+	return resp, actualURL, nil
+}
 
-	return nil
+// readLimited 读取全部数据, 超过 limit 字节时返回错误
+func readLimited(r io.Reader, limit int64) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, service.UpstreamRequestError("读取数据", err)
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("%w: 文件超过大小限制 (%d bytes)", service.ErrMediaTooLarge, limit)
+	}
+	return data, nil
 }
 
 // isMP4 检查视频是否为MP4格式
@@ -499,8 +629,10 @@ func isMP4(data []byte, contentType string) bool {
 	return false
 }
 
-// convertToMP4 将视频转换为MP4格式
-func convertToMP4(videoData []byte) ([]byte, error) {
+// convertToMP4 按转码参数将视频转换为MP4格式, videoFilter 不为空时作为视频过滤器(-vf)使用
+//
+// ctx 取消或超时时终止FFmpeg进程
+func convertToMP4(ctx context.Context, ffmpeg string, videoData []byte, videoFilter string, profile settings.TranscodeProfile) ([]byte, error) {
 	// 创建临时输入文件
 	tempInFile, err := os.CreateTemp("", "video-in-*")
 	if err != nil {
@@ -523,15 +655,21 @@ func convertToMP4(videoData []byte) ([]byte, error) {
 	tempOutFile.Close()
 
 	// 使用FFmpeg进行转换
-	cmd := exec.Command("ffmpeg",
-		"-i", tempInFile.Name(),
-		"-c:v", "libx264", // 使用H.264编码
-		"-preset", "fast", // 快速编码
+	args := []string{"-i", tempInFile.Name()}
+	if videoFilter = profile.VideoFilter(videoFilter); videoFilter != "" {
+		args = append(args, "-vf", videoFilter)
+	}
+	args = append(args, profile.EncodeArgs()...) // 使用H.264编码
+	args = append(args,
 		"-c:a", "aac", // 音频使用AAC编码
 		"-y", // 覆盖输出文件
 		tempOutFile.Name())
+	cmd := exec.CommandContext(ctx, ffmpeg, args...)
 
-	if output, err := cmd.CombinedOutput(); err != nil {
+	start := time.Now()
+	output, err := cmd.CombinedOutput()
+	metrics.ObserveFFmpeg("transcode", start, err)
+	if err != nil {
 		return nil, fmt.Errorf("FFmpeg转换失败: %v, 输出: %s", err, string(output))
 	}
 
@@ -539,17 +677,22 @@ func convertToMP4(videoData []byte) ([]byte, error) {
 	return os.ReadFile(tempOutFile.Name())
 }
 
-func NewCommonHandler(router fiber.Router, repository *repositories.ToolRepository, redis *redis.Client, cos *cos.Client, config *config.EnvConfig) {
+func NewCommonHandler(router fiber.Router, repository *repositories.ToolRepository, redis redis.UniversalClient, cos *cos.Client, client *upstream.Client, settings *settings.Manager, mediaCache *mediacache.Cache, config *config.EnvConfig) {
 	handler := &CommonHandler{
-		redis:      redis,
-		cos:        cos,
-		repository: repository,
-		config:     config,
+		redis:            redis,
+		cos:              cos,
+		client:           client,
+		repository:       repository,
+		config:           config,
+		watermarkFilters: mustWatermarkFilters(config),
+		settings:         settings,
+		mediaCache:       mediaCache,
 	}
 	commonRouter := router.Group("/tools")
 	commonRouter.Post("/parse", handler.ParseShareUrl)
 	commonRouter.Get("/list", handler.GetTools)
 	commonRouter.Post("/", handler.CreateTool)
 	commonRouter.Post("/file/upload", handler.Upload)
-	commonRouter.Get("/media-proxy", handler.ProxyMedia)
+	commonRouter.Get("/media-proxy", requireFlag(settings, FlagMediaProxy, mediaPlatform), handler.ProxyMedia)
+	commonRouter.Get("/media-bundle", requireFlag(settings, FlagMediaBundle, nil), handler.MediaBundle)
 }
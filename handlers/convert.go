@@ -0,0 +1,264 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/config"
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/models"
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/response"
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/service"
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/settings"
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/upstream"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/tencentyun/cos-go-sdk-v5"
+)
+
+type ConvertHandler struct {
+	cos              *cos.Client
+	jobs             *service.JobManager
+	client           *upstream.Client // 下载远程媒体的出站客户端
+	config           *config.EnvConfig
+	soffice          string                             // LibreOffice可执行文件路径, 为空表示未启用
+	watermarkFilters map[string]service.WatermarkFilter // 各平台去水印过滤器
+	settings         *settings.Manager                  // 运行时配置
+}
+
+// ConvertOfficeRequest Office文档转PDF请求
+type ConvertOfficeRequest struct {
+	Key string `json:"key"` // 已上传的docx/xlsx/pptx文件key
+}
+
+// ConvertTranscodeRequest 视频转码请求
+type ConvertTranscodeRequest struct {
+	URL      string `json:"url"`       // 视频地址
+	AudioURL string `json:"audio_url"` // 音频地址, 音视频分离(DASH)时与视频合并
+	Clean    bool   `json:"clean"`     // 是否按平台配置去除水印
+	Profile  string `json:"profile"`   // 转码参数名称, 为空时使用 default
+}
+
+// ConvertOffice godoc
+// @Summary Office文档转PDF
+// @Description 创建Office文档(docx/xlsx/pptx)转PDF的异步任务, 通过 /jobs/{id} 查询结果
+// @Tags convert
+// @Accept json
+// @Produce json
+// @Param request body ConvertOfficeRequest true "文件key"
+// @Success 202 {object} response.Response{data=models.Job}
+// @Failure 400 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Failure 503 {object} response.Response
+// @Router /convert/office [post]
+func (h *ConvertHandler) ConvertOffice(ctx *fiber.Ctx) error {
+	if h.soffice == "" {
+		return response.Fail(ctx, fiber.StatusServiceUnavailable, "Office conversion is not available")
+	}
+	req := &ConvertOfficeRequest{}
+	if err := ctx.BodyParser(req); err != nil || req.Key == "" {
+		return response.Fail(ctx, fiber.StatusBadRequest, "Key is required")
+	}
+	if !service.OfficeExtensions[strings.ToLower(filepath.Ext(req.Key))] {
+		return response.Fail(ctx, fiber.StatusBadRequest, "Invalid file type Please upload a docx, xlsx or pptx file")
+	}
+
+	job, err := h.jobs.Submit(ctx.UserContext(), models.JobTypeOfficeToPDF, map[string]string{"key": req.Key})
+	if errors.Is(err, service.ErrJobQueueFull) {
+		return err
+	}
+	if err != nil {
+		requestLog(ctx).Errorf("submit office job: %v", err)
+		return response.Fail(ctx, fiber.StatusInternalServerError, "Create job failed")
+	}
+	return response.SuccessWithStatus(ctx, fiber.StatusAccepted, "Create job success", job)
+}
+
+// ConvertTranscode godoc
+// @Summary 视频转码
+// @Description 创建将远程视频转码为MP4的异步任务, 可合并分离的音视频流(如B站DASH), 可选按平台配置去除水印, 通过 /jobs/{id} 查询结果
+// @Tags convert
+// @Accept json
+// @Produce json
+// @Param request body ConvertTranscodeRequest true "视频地址和转码选项"
+// @Success 202 {object} response.Response{data=models.Job}
+// @Failure 400 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Failure 503 {object} response.Response
+// @Router /convert/transcode [post]
+func (h *ConvertHandler) ConvertTranscode(ctx *fiber.Ctx) error {
+	req := &ConvertTranscodeRequest{}
+	if err := ctx.BodyParser(req); err != nil {
+		return response.Fail(ctx, fiber.StatusBadRequest, "Invalid request body")
+	}
+	if !isHTTPURL(req.URL) || (req.AudioURL != "" && !isHTTPURL(req.AudioURL)) {
+		return response.Fail(ctx, fiber.StatusBadRequest, "Invalid url")
+	}
+	// 与媒体代理相同的域名限制, 只允许访问平台域名; 解析到内网的地址由出站客户端在连接时拒绝
+	runtime := h.settings.Get()
+	if !allowedMediaURL(runtime, req.URL) || (req.AudioURL != "" && !allowedMediaURL(runtime, req.AudioURL)) {
+		return response.Fail(ctx, fiber.StatusForbidden, "Host not allowed")
+	}
+	if _, ok := h.settings.Get().TranscodeProfile(req.Profile); !ok {
+		return response.Fail(ctx, fiber.StatusBadRequest, "Unknown transcode profile")
+	}
+
+	job, err := h.jobs.Submit(ctx.UserContext(), models.JobTypeTranscode, map[string]string{
+		"url":       req.URL,
+		"audio_url": req.AudioURL,
+		"clean":     strconv.FormatBool(req.Clean),
+		"profile":   req.Profile,
+	})
+	if errors.Is(err, service.ErrJobQueueFull) {
+		return err
+	}
+	if err != nil {
+		requestLog(ctx).Errorf("submit transcode job: %v", err)
+		return response.Fail(ctx, fiber.StatusInternalServerError, "Create job failed")
+	}
+	return response.SuccessWithStatus(ctx, fiber.StatusAccepted, "Create job success", job)
+}
+
+// runOfficeToPDF 下载Office文档, 转换为PDF后上传, 返回PDF文件key
+func (h *ConvertHandler) runOfficeToPDF(ctx context.Context, job *models.Job) (string, error) {
+	workDir, err := os.MkdirTemp("", "office-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(workDir)
+
+	inFile, err := downloadObject(ctx, h.cos, job.Params["key"], workDir)
+	if err != nil {
+		return "", err
+	}
+
+	convertCtx, cancel := context.WithTimeout(ctx, h.config.ToolConfig.ConvertTimeout)
+	defer cancel()
+	outFile, err := service.ConvertOfficeToPDF(convertCtx, h.soffice, inFile)
+	if err != nil {
+		return "", err
+	}
+	return uploadObject(ctx, h.cos, outFile, filepath.Base(outFile))
+}
+
+// runTranscode 下载远程视频, 转码为MP4(可选去水印)后上传, 返回视频文件key
+//
+// 指定了 audio_url 时视为音视频分离的DASH流, 下载后合并为一个MP4
+func (h *ConvertHandler) runTranscode(ctx context.Context, job *models.Job) (string, error) {
+	mediaURL := job.Params["url"]
+	audioURL := job.Params["audio_url"]
+
+	// 提交后允许的域名可能已修改, 执行时再次检查
+	runtime := h.settings.Get()
+	if !allowedMediaURL(runtime, mediaURL) || (audioURL != "" && !allowedMediaURL(runtime, audioURL)) {
+		return "", fmt.Errorf("不允许访问该域名")
+	}
+
+	var videoFilter string
+	if job.Params["clean"] == "true" {
+		if filter, ok := h.watermarkFilters[service.MediaPlatform(mediaURL)]; ok {
+			videoFilter = filter.String()
+		}
+	}
+
+	// 提交任务后转码参数被删除时使用默认参数
+	profile, ok := h.settings.Get().TranscodeProfile(job.Params["profile"])
+	if !ok {
+		profile, _ = h.settings.Get().TranscodeProfile(settings.DefaultTranscodeProfile)
+	}
+
+	workDir, err := os.MkdirTemp("", "transcode-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(workDir)
+
+	videoFile := filepath.Join(workDir, "video-in")
+	if err := h.downloadMedia(ctx, mediaURL, videoFile); err != nil {
+		return "", fmt.Errorf("获取视频失败: %v", err)
+	}
+
+	outFile := filepath.Join(workDir, "video.mp4")
+	if audioURL != "" {
+		audioFile := filepath.Join(workDir, "audio-in")
+		if err := h.downloadMedia(ctx, audioURL, audioFile); err != nil {
+			return "", fmt.Errorf("获取音频失败: %v", err)
+		}
+		if err := service.MuxToMP4(ctx, h.config.ToolConfig.FfmpegPath, videoFile, audioFile, outFile, videoFilter, profile); err != nil {
+			return "", err
+		}
+	} else {
+		videoData, err := os.ReadFile(videoFile)
+		if err != nil {
+			return "", err
+		}
+		converted, err := convertToMP4(ctx, h.config.ToolConfig.FfmpegPath, videoData, videoFilter, profile)
+		if err != nil {
+			return "", err
+		}
+		if err := os.WriteFile(outFile, converted, 0o600); err != nil {
+			return "", err
+		}
+	}
+	return uploadObject(ctx, h.cos, outFile, "video.mp4")
+}
+
+// downloadMedia 下载远程媒体文件到本地, 超过大小限制时返回错误
+func (h *ConvertHandler) downloadMedia(ctx context.Context, mediaURL, localPath string) error {
+	// 下载时长由任务超时控制, 不使用域名策略的请求超时
+	resp, _, err := h.client.GetFirst(upstream.WithTimeout(ctx, 0), []string{mediaURL}, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	file, err := os.Create(localPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	limit := maxFileBytes(h.config)
+	written, err := io.Copy(file, io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return err
+	}
+	if written > limit {
+		return fmt.Errorf("文件超过大小限制 (%d bytes)", limit)
+	}
+	return nil
+}
+
+// isHTTPURL 判断是否为http/https链接
+func isHTTPURL(url string) bool {
+	return strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://")
+}
+
+func NewConvertHandler(router fiber.Router, cos *cos.Client, jobs *service.JobManager, client *upstream.Client, settings *settings.Manager, config *config.EnvConfig) {
+	handler := &ConvertHandler{
+		cos:              cos,
+		jobs:             jobs,
+		client:           client,
+		config:           config,
+		watermarkFilters: mustWatermarkFilters(config),
+		settings:         settings,
+	}
+	jobs.Register(models.JobTypeTranscode, handler.runTranscode)
+	if path := config.ToolConfig.SofficePath; path != "" {
+		if soffice, err := exec.LookPath(path); err != nil {
+			log.Warnf("LibreOffice not found (%s), office conversion disabled: %v", path, err)
+		} else {
+			handler.soffice = soffice
+			jobs.Register(models.JobTypeOfficeToPDF, handler.runOfficeToPDF)
+		}
+	}
+	convertRouter := router.Group("/convert")
+	convertRouter.Post("/office", requireFlag(settings, FlagConvertOffice, nil), handler.ConvertOffice)
+	convertRouter.Post("/transcode", requireFlag(settings, FlagConvertTranscode, nil), handler.ConvertTranscode)
+}
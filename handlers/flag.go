@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/response"
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/service"
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/settings"
+	"github.com/gofiber/fiber/v2"
+)
+
+// 接口的功能开关名称, 平台解析的开关为 service.FlagParse
+const (
+	FlagMediaProxy       = "media_proxy"
+	FlagMediaBundle      = "media_bundle"
+	FlagConvertOffice    = "convert_office"
+	FlagConvertTranscode = "convert_transcode"
+)
+
+// requireFlag 功能开关对当前请求关闭时返回503, platform 不为空时用于获取请求所属平台
+func requireFlag(settings *settings.Manager, name string, platform func(ctx *fiber.Ctx) string) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		var requestPlatform string
+		if platform != nil {
+			requestPlatform = platform(ctx)
+		}
+		if !settings.Get().FlagEnabled(name, requestPlatform, settingsSubject(ctx)) {
+			return response.NewError(fiber.StatusServiceUnavailable, response.CodeFeatureDisabled, "该功能暂时无法使用, 请稍后再试")
+		}
+		return ctx.Next()
+	}
+}
+
+// mediaPlatform 返回媒体代理请求的地址所属平台
+func mediaPlatform(ctx *fiber.Ctx) string {
+	return service.MediaPlatform(ctx.Query("url"))
+}
+
+// settingsSubject 返回灰度分桶使用的请求方标识
+func settingsSubject(ctx *fiber.Ctx) string {
+	return settings.Subject(ctx.UserContext())
+}
+
+type FlagHandler struct {
+	settings *settings.Manager
+}
+
+// GetFlags godoc
+// @Summary 查询功能开关
+// @Description 返回所有已配置的功能开关, 未配置的功能默认开启
+// @Tags admin
+// @Produce json
+// @Security AdminToken
+// @Success 200 {object} response.Response{data=map[string]settings.Flag}
+// @Failure 401 {object} response.Response
+// @Router /admin/flags [get]
+func (h *FlagHandler) GetFlags(ctx *fiber.Ctx) error {
+	return response.Success(ctx, "Get flags success", h.settings.Get().Flags)
+}
+
+// UpdateFlag godoc
+// @Summary 新增或修改功能开关
+// @Description 修改后所有实例立即生效. 接口开关: media_proxy、media_bundle、convert_office、convert_transcode; 平台解析开关: parse, 在 platforms 中关闭单个平台
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security AdminToken
+// @Param name path string true "开关名称"
+// @Param flag body settings.Flag true "开关配置"
+// @Success 200 {object} response.Response{data=map[string]settings.Flag}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /admin/flags/{name} [put]
+func (h *FlagHandler) UpdateFlag(ctx *fiber.Ctx) error {
+	flag := settings.Flag{}
+	if err := json.Unmarshal(ctx.Body(), &flag); err != nil {
+		return response.Fail(ctx, fiber.StatusBadRequest, "Invalid request body")
+	}
+	current, err := h.settings.SetFlag(ctx.UserContext(), ctx.Params("name"), flag)
+	if err != nil {
+		return h.flagError(ctx, err)
+	}
+	return response.Success(ctx, "Update flag success", current.Flags)
+}
+
+// DeleteFlag godoc
+// @Summary 删除功能开关
+// @Description 删除后该功能恢复默认开启
+// @Tags admin
+// @Produce json
+// @Security AdminToken
+// @Param name path string true "开关名称"
+// @Success 200 {object} response.Response{data=map[string]settings.Flag}
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /admin/flags/{name} [delete]
+func (h *FlagHandler) DeleteFlag(ctx *fiber.Ctx) error {
+	current, err := h.settings.DeleteFlag(ctx.UserContext(), ctx.Params("name"))
+	if err != nil {
+		return h.flagError(ctx, err)
+	}
+	return response.Success(ctx, "Delete flag success", current.Flags)
+}
+
+func (h *FlagHandler) flagError(ctx *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, settings.ErrUnknownSetting):
+		return response.Fail(ctx, fiber.StatusNotFound, "Flag not found")
+	case errors.Is(err, settings.ErrInvalidSetting):
+		return response.Fail(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	requestLog(ctx).Errorf("save flag: %v", err)
+	return response.Fail(ctx, fiber.StatusInternalServerError, "Save flag failed")
+}
+
+func NewFlagHandler(router fiber.Router, settings *settings.Manager) {
+	handler := &FlagHandler{
+		settings: settings,
+	}
+	flagRouter := router.Group("/flags")
+	flagRouter.Get("/", handler.GetFlags)
+	flagRouter.Put("/:name", handler.UpdateFlag)
+	flagRouter.Delete("/:name", handler.DeleteFlag)
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/config"
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/response"
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/service"
+	"github.com/gofiber/fiber/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/tencentyun/cos-go-sdk-v5"
+)
+
+// imageCacheKeyPrefix 图片处理结果在Redis中的key前缀
+const imageCacheKeyPrefix = "image:"
+
+type ImageHandler struct {
+	redis  redis.UniversalClient
+	cos    *cos.Client
+	config *config.EnvConfig
+}
+
+// ImageProcessRequest 处理已上传图片的请求
+type ImageProcessRequest struct {
+	Key string `json:"key"` // 已上传的图片文件key
+	service.ImageOptions
+}
+
+// Process godoc
+// @Summary 处理已上传的图片
+// @Description 对已上传的图片进行缩放、裁剪、按EXIF方向旋转、格式转换(JPEG/PNG/WebP)和压缩, 结果写回对象存储
+// @Tags image
+// @Accept json
+// @Produce json
+// @Param request body ImageProcessRequest true "文件key和处理参数"
+// @Success 200 {object} response.Response{data=response.KeyData}
+// @Failure 400 {object} response.Response
+// @Failure 422 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /image/process [post]
+func (h *ImageHandler) Process(ctx *fiber.Ctx) error {
+	req := &ImageProcessRequest{}
+	if err := ctx.BodyParser(req); err != nil || req.Key == "" {
+		return response.Fail(ctx, fiber.StatusBadRequest, "Key is required")
+	}
+	if err := req.Normalize(); err != nil {
+		return response.Fail(ctx, fiber.StatusBadRequest, err.Error())
+	}
+
+	// 相同文件和参数的处理结果直接复用
+	cacheKey := imageCacheKeyPrefix + "object:" + hashKey(req.Key+"|"+req.CacheKey())
+	if key, err := h.redis.Get(ctx.UserContext(), cacheKey).Result(); err == nil {
+		return response.Success(ctx, "Process image success", response.KeyData{Key: key})
+	}
+
+	workDir, err := os.MkdirTemp("", "image-*")
+	if err != nil {
+		return response.Fail(ctx, fiber.StatusInternalServerError, "Create work dir failed")
+	}
+	defer os.RemoveAll(workDir)
+
+	inFile, err := downloadObject(ctx.UserContext(), h.cos, req.Key, workDir)
+	if err != nil {
+		requestLog(ctx).Errorf("image process: %v", err)
+		return response.Fail(ctx, fiber.StatusBadRequest, "Download file failed")
+	}
+	source, err := os.ReadFile(inFile)
+	if err != nil {
+		return response.Fail(ctx, fiber.StatusInternalServerError, "Read file failed")
+	}
+
+	data, contentType, err := service.ProcessImage(ctx.UserContext(), h.config.ToolConfig.FfmpegPath, source, req.ImageOptions)
+	if err != nil {
+		requestLog(ctx).Errorf("image process: %v", err)
+		return response.Fail(ctx, fiber.StatusUnprocessableEntity, "Process image failed")
+	}
+
+	name := strings.TrimSuffix(filepath.Base(req.Key), filepath.Ext(req.Key)) + "." + strings.TrimPrefix(contentType, "image/")
+	outFile := filepath.Join(workDir, "out-"+name)
+	if err := os.WriteFile(outFile, data, 0o600); err != nil {
+		return response.Fail(ctx, fiber.StatusInternalServerError, "Write file failed")
+	}
+	key, err := uploadObject(ctx.UserContext(), h.cos, outFile, name)
+	if err != nil {
+		requestLog(ctx).Errorf("image process: %v", err)
+		return response.Fail(ctx, fiber.StatusInternalServerError, "Upload file failed")
+	}
+	if err := h.redis.Set(ctx.UserContext(), cacheKey, key, h.config.MediaConfig.ImageCacheTTL).Err(); err != nil {
+		requestLog(ctx).Warnf("cache image result: %v", err)
+	}
+	return response.Success(ctx, "Process image success", response.KeyData{Key: key})
+}
+
+// hashKey 对任意长度的字符串取sha256, 用作缓存key
+func hashKey(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func NewImageHandler(router fiber.Router, redis redis.UniversalClient, cos *cos.Client, config *config.EnvConfig) {
+	handler := &ImageHandler{
+		redis:  redis,
+		cos:    cos,
+		config: config,
+	}
+	imageRouter := router.Group("/image")
+	imageRouter.Post("/process", handler.Process)
+}
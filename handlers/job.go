@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/response"
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/service"
+	"github.com/gofiber/fiber/v2"
+)
+
+type JobHandler struct {
+	jobs *service.JobManager
+}
+
+// GetJob godoc
+// @Summary 查询异步任务
+// @Description 查询异步任务的执行状态和结果文件key
+// @Tags jobs
+// @Produce json
+// @Param id path string true "任务ID"
+// @Success 200 {object} response.Response{data=models.Job}
+// @Failure 404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /jobs/{id} [get]
+func (h *JobHandler) GetJob(ctx *fiber.Ctx) error {
+	job, err := h.jobs.Get(ctx.UserContext(), ctx.Params("id"))
+	if errors.Is(err, service.ErrJobNotFound) {
+		return response.Fail(ctx, fiber.StatusNotFound, "Job not found")
+	}
+	if err != nil {
+		requestLog(ctx).Errorf("get job: %v", err)
+		return response.Fail(ctx, fiber.StatusInternalServerError, "Get job failed")
+	}
+	return response.Success(ctx, "Get job success", job)
+}
+
+func NewJobHandler(router fiber.Router, jobs *service.JobManager) {
+	handler := &JobHandler{
+		jobs: jobs,
+	}
+	jobRouter := router.Group("/jobs")
+	jobRouter.Get("/:id", handler.GetJob)
+}
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/models"
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/response"
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/service"
+	"github.com/gofiber/fiber/v2"
+)
+
+type ParseStatusHandler struct {
+	prober *service.ParseProber
+}
+
+// GetParseStatus godoc
+// @Summary 查询平台解析健康状态
+// @Description 返回各平台最近一次探测链接解析结果、支持的平台和分享链接域名, 以及出站请求的熔断状态
+// @Tags tools
+// @Produce json
+// @Success 200 {object} response.Response{data=models.ParseStatus}
+// @Failure 500 {object} response.Response
+// @Router /tools/parse/status [get]
+func (h *ParseStatusHandler) GetParseStatus(ctx *fiber.Ctx) error {
+	health, err := h.prober.Status(ctx.UserContext())
+	if err != nil {
+		requestLog(ctx).Errorf("get parse status: %v", err)
+		return response.Fail(ctx, fiber.StatusInternalServerError, "Get parse status failed")
+	}
+	return response.Success(ctx, "Get parse status success", models.ParseStatus{
+		Health:    health,
+		Platforms: service.SupportedPlatforms(),
+		Breakers:  service.CircuitBreakers(),
+	})
+}
+
+func NewParseStatusHandler(router fiber.Router, prober *service.ParseProber) {
+	handler := &ParseStatusHandler{
+		prober: prober,
+	}
+	router.Get("/tools/parse/status", handler.GetParseStatus)
+}
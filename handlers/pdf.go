@@ -0,0 +1,331 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/config"
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/response"
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/service"
+	"github.com/gofiber/fiber/v2"
+	"github.com/tencentyun/cos-go-sdk-v5"
+)
+
+type PdfHandler struct {
+	cos    *cos.Client
+	config *config.EnvConfig
+}
+
+// PdfMergeRequest 合并PDF请求
+type PdfMergeRequest struct {
+	Keys []string `json:"keys"` // 按顺序合并的PDF文件key
+}
+
+// PdfSplitRequest 拆分PDF请求
+type PdfSplitRequest struct {
+	Key    string   `json:"key"`
+	Ranges []string `json:"ranges"` // 页码范围, 如 ["1-3", "4-"]
+}
+
+// PdfImagesRequest PDF转图片请求
+type PdfImagesRequest struct {
+	Key       string `json:"key"`
+	Format    string `json:"format"`     // png/jpeg, 默认png
+	DPI       int    `json:"dpi"`        // 分辨率, 默认150
+	FirstPage int    `json:"first_page"` // 起始页, 0表示第一页
+	LastPage  int    `json:"last_page"`  // 结束页, 0表示最后一页
+}
+
+// PdfWatermarkRequest PDF文字水印请求
+type PdfWatermarkRequest struct {
+	Key     string  `json:"key"`
+	Text    string  `json:"text"`
+	Pages   string  `json:"pages"`   // 页码范围, 为空时处理所有页面
+	Opacity float64 `json:"opacity"` // 不透明度(0-1], 默认0.3
+}
+
+// PdfKeyRequest 仅包含文件key的请求
+type PdfKeyRequest struct {
+	Key string `json:"key"`
+}
+
+// Merge godoc
+// @Summary 合并PDF
+// @Description 按顺序合并多个已上传的PDF文件, 结果写回对象存储
+// @Tags pdf
+// @Accept json
+// @Produce json
+// @Param request body PdfMergeRequest true "待合并的文件key"
+// @Success 200 {object} response.Response{data=response.KeyData}
+// @Failure 400 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /pdf/merge [post]
+func (h *PdfHandler) Merge(ctx *fiber.Ctx) error {
+	req := &PdfMergeRequest{}
+	if err := ctx.BodyParser(req); err != nil {
+		return response.Fail(ctx, fiber.StatusBadRequest, "Invalid request body")
+	}
+	if len(req.Keys) < 2 {
+		return response.Fail(ctx, fiber.StatusBadRequest, "At least two files are required")
+	}
+
+	workDir, err := os.MkdirTemp("", "pdf-merge-*")
+	if err != nil {
+		return response.Fail(ctx, fiber.StatusInternalServerError, "Create work dir failed")
+	}
+	defer os.RemoveAll(workDir)
+
+	inFiles := make([]string, 0, len(req.Keys))
+	for i, key := range req.Keys {
+		// 每个文件使用独立子目录, 避免同名文件互相覆盖
+		dir := filepath.Join(workDir, strconv.Itoa(i))
+		if err := os.Mkdir(dir, 0o700); err != nil {
+			return response.Fail(ctx, fiber.StatusInternalServerError, "Create work dir failed")
+		}
+		inFile, err := downloadObject(ctx.UserContext(), h.cos, key, dir)
+		if errors.Is(err, service.ErrObjectNotFound) {
+			return err
+		}
+		if err != nil {
+			requestLog(ctx).Errorf("pdf merge: %v", err)
+			return response.Fail(ctx, fiber.StatusBadRequest, "Download file failed")
+		}
+		inFiles = append(inFiles, inFile)
+	}
+
+	outFile := filepath.Join(workDir, "merged.pdf")
+	if err := service.MergePDF(inFiles, outFile); err != nil {
+		requestLog(ctx).Errorf("pdf merge: %v", err)
+		return response.Fail(ctx, fiber.StatusInternalServerError, "Merge pdf failed")
+	}
+	key, err := uploadObject(ctx.UserContext(), h.cos, outFile, "merged.pdf")
+	if err != nil {
+		requestLog(ctx).Errorf("pdf merge: %v", err)
+		return response.Fail(ctx, fiber.StatusInternalServerError, "Upload file failed")
+	}
+	return response.Success(ctx, "Merge pdf success", response.KeyData{Key: key})
+}
+
+// Split godoc
+// @Summary 拆分PDF
+// @Description 按页码范围拆分PDF, 每个范围生成一个新文件
+// @Tags pdf
+// @Accept json
+// @Produce json
+// @Param request body PdfSplitRequest true "文件key和页码范围"
+// @Success 200 {object} response.Response{data=response.KeysData}
+// @Failure 400 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /pdf/split [post]
+func (h *PdfHandler) Split(ctx *fiber.Ctx) error {
+	req := &PdfSplitRequest{}
+	if err := ctx.BodyParser(req); err != nil {
+		return response.Fail(ctx, fiber.StatusBadRequest, "Invalid request body")
+	}
+	if req.Key == "" || len(req.Ranges) == 0 {
+		return response.Fail(ctx, fiber.StatusBadRequest, "Key and ranges are required")
+	}
+
+	keys, err := h.processMulti(ctx.UserContext(), req.Key, func(inFile, outDir string) ([]string, error) {
+		return service.SplitPDF(inFile, outDir, req.Ranges)
+	})
+	if err != nil {
+		return pdfError(ctx, "split", err, "Split pdf failed")
+	}
+	return response.Success(ctx, "Split pdf success", response.KeysData{Keys: keys})
+}
+
+// Images godoc
+// @Summary PDF转图片
+// @Description 将PDF页面渲染为PNG/JPEG图片
+// @Tags pdf
+// @Accept json
+// @Produce json
+// @Param request body PdfImagesRequest true "文件key和渲染参数"
+// @Success 200 {object} response.Response{data=response.KeysData}
+// @Failure 400 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /pdf/images [post]
+func (h *PdfHandler) Images(ctx *fiber.Ctx) error {
+	req := &PdfImagesRequest{}
+	if err := ctx.BodyParser(req); err != nil {
+		return response.Fail(ctx, fiber.StatusBadRequest, "Invalid request body")
+	}
+	if req.Key == "" {
+		return response.Fail(ctx, fiber.StatusBadRequest, "Key is required")
+	}
+	if req.FirstPage < 0 || req.LastPage < 0 || (req.LastPage > 0 && req.LastPage < req.FirstPage) {
+		return response.Fail(ctx, fiber.StatusBadRequest, "Invalid page range")
+	}
+
+	keys, err := h.processMulti(ctx.UserContext(), req.Key, func(inFile, outDir string) ([]string, error) {
+		return service.PDFPagesToImages(ctx.UserContext(), h.config.ToolConfig.PdftoppmPath, inFile, outDir, req.Format, req.DPI, req.FirstPage, req.LastPage)
+	})
+	if err != nil {
+		return pdfError(ctx, "images", err, "Convert pdf to images failed")
+	}
+	return response.Success(ctx, "Convert pdf to images success", response.KeysData{Keys: keys})
+}
+
+// Compress godoc
+// @Summary 压缩PDF
+// @Description 优化PDF结构以减小文件体积
+// @Tags pdf
+// @Accept json
+// @Produce json
+// @Param request body PdfKeyRequest true "文件key"
+// @Success 200 {object} response.Response{data=response.KeyData}
+// @Failure 400 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /pdf/compress [post]
+func (h *PdfHandler) Compress(ctx *fiber.Ctx) error {
+	req := &PdfKeyRequest{}
+	if err := ctx.BodyParser(req); err != nil || req.Key == "" {
+		return response.Fail(ctx, fiber.StatusBadRequest, "Key is required")
+	}
+
+	key, err := h.processSingle(ctx.UserContext(), req.Key, "compressed.pdf", service.CompressPDF)
+	if err != nil {
+		return pdfError(ctx, "compress", err, "Compress pdf failed")
+	}
+	return response.Success(ctx, "Compress pdf success", response.KeyData{Key: key})
+}
+
+// Watermark godoc
+// @Summary PDF添加文字水印
+// @Description 为PDF的指定页面添加文字水印
+// @Tags pdf
+// @Accept json
+// @Produce json
+// @Param request body PdfWatermarkRequest true "文件key和水印参数"
+// @Success 200 {object} response.Response{data=response.KeyData}
+// @Failure 400 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /pdf/watermark [post]
+func (h *PdfHandler) Watermark(ctx *fiber.Ctx) error {
+	req := &PdfWatermarkRequest{}
+	if err := ctx.BodyParser(req); err != nil {
+		return response.Fail(ctx, fiber.StatusBadRequest, "Invalid request body")
+	}
+	if req.Key == "" || strings.TrimSpace(req.Text) == "" {
+		return response.Fail(ctx, fiber.StatusBadRequest, "Key and text are required")
+	}
+
+	key, err := h.processSingle(ctx.UserContext(), req.Key, "watermarked.pdf", func(inFile, outFile string) error {
+		return service.WatermarkPDF(inFile, outFile, req.Text, req.Pages, req.Opacity)
+	})
+	if err != nil {
+		return pdfError(ctx, "watermark", err, "Add watermark failed")
+	}
+	return response.Success(ctx, "Add watermark success", response.KeyData{Key: key})
+}
+
+// Text godoc
+// @Summary 提取PDF文本
+// @Description 提取PDF中的纯文本, 结果以txt文件写回对象存储
+// @Tags pdf
+// @Accept json
+// @Produce json
+// @Param request body PdfKeyRequest true "文件key"
+// @Success 200 {object} response.Response{data=response.KeyData}
+// @Failure 400 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /pdf/text [post]
+func (h *PdfHandler) Text(ctx *fiber.Ctx) error {
+	req := &PdfKeyRequest{}
+	if err := ctx.BodyParser(req); err != nil || req.Key == "" {
+		return response.Fail(ctx, fiber.StatusBadRequest, "Key is required")
+	}
+
+	key, err := h.processSingle(ctx.UserContext(), req.Key, "text.txt", func(inFile, outFile string) error {
+		return service.ExtractPDFText(ctx.UserContext(), h.config.ToolConfig.PdftotextPath, inFile, outFile)
+	})
+	if err != nil {
+		return pdfError(ctx, "text", err, "Extract text failed")
+	}
+	return response.Success(ctx, "Extract text success", response.KeyData{Key: key})
+}
+
+// pdfError 文件不存在和页码范围无效交由统一错误处理返回404/400, 其他错误记录日志后返回500
+func pdfError(ctx *fiber.Ctx, op string, err error, message string) error {
+	if errors.Is(err, service.ErrObjectNotFound) || errors.Is(err, service.ErrInvalidPageRange) {
+		return err
+	}
+	requestLog(ctx).Errorf("pdf %s: %v", op, err)
+	return response.Fail(ctx, fiber.StatusInternalServerError, message)
+}
+
+// processSingle 下载文件, 执行单输出处理并上传结果, 返回结果文件key
+func (h *PdfHandler) processSingle(ctx context.Context, key, outName string, process func(inFile, outFile string) error) (string, error) {
+	workDir, err := os.MkdirTemp("", "pdf-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(workDir)
+
+	inFile, err := downloadObject(ctx, h.cos, key, workDir)
+	if err != nil {
+		return "", err
+	}
+	outFile := filepath.Join(workDir, outName)
+	if err := process(inFile, outFile); err != nil {
+		return "", err
+	}
+	return uploadObject(ctx, h.cos, outFile, outName)
+}
+
+// processMulti 下载文件, 执行多输出处理并逐个上传结果, 返回结果文件key列表
+func (h *PdfHandler) processMulti(ctx context.Context, key string, process func(inFile, outDir string) ([]string, error)) ([]string, error) {
+	workDir, err := os.MkdirTemp("", "pdf-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(workDir)
+
+	inFile, err := downloadObject(ctx, h.cos, key, workDir)
+	if err != nil {
+		return nil, err
+	}
+	outDir := filepath.Join(workDir, "out")
+	if err := os.Mkdir(outDir, 0o700); err != nil {
+		return nil, err
+	}
+	outFiles, err := process(inFile, outDir)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(outFiles))
+	for _, outFile := range outFiles {
+		key, err := uploadObject(ctx, h.cos, outFile, filepath.Base(outFile))
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+func NewPdfHandler(router fiber.Router, cos *cos.Client, config *config.EnvConfig) {
+	handler := &PdfHandler{
+		cos:    cos,
+		config: config,
+	}
+	pdfRouter := router.Group("/pdf")
+	pdfRouter.Post("/merge", handler.Merge)
+	pdfRouter.Post("/split", handler.Split)
+	pdfRouter.Post("/images", handler.Images)
+	pdfRouter.Post("/compress", handler.Compress)
+	pdfRouter.Post("/watermark", handler.Watermark)
+	pdfRouter.Post("/text", handler.Text)
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/response"
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/settings"
+	"github.com/gofiber/fiber/v2"
+)
+
+type SettingsHandler struct {
+	settings *settings.Manager
+}
+
+// GetSettings godoc
+// @Summary 查询运行时配置
+// @Description 返回当前实例生效的运行时配置
+// @Tags admin
+// @Produce json
+// @Security AdminToken
+// @Success 200 {object} response.Response{data=settings.Settings}
+// @Failure 401 {object} response.Response
+// @Router /admin/settings [get]
+func (h *SettingsHandler) GetSettings(ctx *fiber.Ctx) error {
+	return response.Success(ctx, "Get settings success", h.settings.Get())
+}
+
+// UpdateSetting godoc
+// @Summary 修改运行时配置项
+// @Description 请求体为配置项的JSON值, 保存后所有实例立即生效, 如 PUT /admin/settings/rate_limit 请求体为 120
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security AdminToken
+// @Param key path string true "配置项名称" Enums(rate_limit, proxy_allowed_hosts, user_agents, transcode_profiles, flags)
+// @Param value body object true "配置项的值"
+// @Success 200 {object} response.Response{data=settings.Settings}
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /admin/settings/{key} [put]
+func (h *SettingsHandler) UpdateSetting(ctx *fiber.Ctx) error {
+	value := json.RawMessage(ctx.Body())
+	if !json.Valid(value) {
+		return response.Fail(ctx, fiber.StatusBadRequest, "Invalid JSON value")
+	}
+	current, err := h.settings.Set(ctx.UserContext(), ctx.Params("key"), value)
+	if err != nil {
+		return h.settingError(ctx, err)
+	}
+	return response.Success(ctx, "Update setting success", current)
+}
+
+// ResetSetting godoc
+// @Summary 恢复运行时配置项的默认值
+// @Tags admin
+// @Produce json
+// @Security AdminToken
+// @Param key path string true "配置项名称" Enums(rate_limit, proxy_allowed_hosts, user_agents, transcode_profiles, flags)
+// @Success 200 {object} response.Response{data=settings.Settings}
+// @Failure 401 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /admin/settings/{key} [delete]
+func (h *SettingsHandler) ResetSetting(ctx *fiber.Ctx) error {
+	current, err := h.settings.Reset(ctx.UserContext(), ctx.Params("key"))
+	if err != nil {
+		return h.settingError(ctx, err)
+	}
+	return response.Success(ctx, "Reset setting success", current)
+}
+
+func (h *SettingsHandler) settingError(ctx *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, settings.ErrUnknownSetting):
+		return response.Fail(ctx, fiber.StatusNotFound, "Setting not found")
+	case errors.Is(err, settings.ErrInvalidSetting):
+		return response.Fail(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	requestLog(ctx).Errorf("save setting: %v", err)
+	return response.Fail(ctx, fiber.StatusInternalServerError, "Save setting failed")
+}
+
+func NewSettingsHandler(router fiber.Router, settings *settings.Manager) {
+	handler := &SettingsHandler{
+		settings: settings,
+	}
+	settingsRouter := router.Group("/settings")
+	settingsRouter.Get("/", handler.GetSettings)
+	settingsRouter.Put("/:key", handler.UpdateSetting)
+	settingsRouter.Delete("/:key", handler.ResetSetting)
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/metrics"
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/service"
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/tracing"
+	"github.com/google/uuid"
+	"github.com/tencentyun/cos-go-sdk-v5"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// newObjectKey 生成对象存储的文件key, 格式为 日期/时间-随机id-文件名
+//
+// 各接口的输出文件名固定(如 merged.pdf), 随机id避免同一秒内的请求写入同一个对象
+func newObjectKey(name string) string {
+	now := time.Now()
+	return now.Format("20060102") + "/" + now.Format("150405-") + uuid.NewString() + "-" + name
+}
+
+// downloadObject 将对象存储中的文件下载到本地目录, 返回本地文件路径
+func downloadObject(ctx context.Context, client *cos.Client, key, dir string) (string, error) {
+	if key == "" || strings.Contains(key, "..") {
+		return "", fmt.Errorf("无效的文件key: %q", key)
+	}
+	localPath := filepath.Join(dir, filepath.Base(key))
+	ctx, span := tracing.Start(ctx, "cos.GetObject", attribute.String("cos.key", key))
+	_, err := client.Object.GetToFile(ctx, key, localPath, nil)
+	tracing.End(span, err)
+	if cos.IsNotFoundError(err) {
+		return "", fmt.Errorf("%w: %s", service.ErrObjectNotFound, key)
+	}
+	if err != nil {
+		return "", fmt.Errorf("下载文件失败 (key: %s): %v", key, err)
+	}
+	return localPath, nil
+}
+
+// uploadObject 将本地文件上传到对象存储, 返回文件key
+func uploadObject(ctx context.Context, client *cos.Client, localPath, name string) (string, error) {
+	file, err := os.Open(localPath)
+	if err != nil {
+		return "", fmt.Errorf("打开文件失败: %v", err)
+	}
+	defer file.Close()
+
+	key := newObjectKey(name)
+	if err := putObject(ctx, client, key, file); err != nil {
+		return "", fmt.Errorf("上传文件失败: %v", err)
+	}
+	return key, nil
+}
+
+// putObject 上传数据到对象存储并记录上传大小
+func putObject(ctx context.Context, client *cos.Client, key string, file io.Reader) error {
+	ctx, span := tracing.Start(ctx, "cos.PutObject", attribute.String("cos.key", key))
+	counter := &countingReader{Reader: file}
+	_, err := client.Object.Put(ctx, key, counter, nil)
+	span.SetAttributes(attribute.Int64("cos.size", counter.n))
+	tracing.End(span, err)
+	if err == nil {
+		metrics.ObserveCOSUpload(counter.n)
+	}
+	return err
+}
+
+// countingReader 统计读取的字节数
+type countingReader struct {
+	io.Reader
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.n += int64(n)
+	return n, err
+}
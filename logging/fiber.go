@@ -0,0 +1,112 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"runtime"
+	"time"
+
+	"github.com/gofiber/fiber/v2/log"
+)
+
+// levelTrace 和 levelFatal 对应 fiber/log 中slog没有的级别
+const (
+	levelTrace = slog.LevelDebug - 4
+	levelFatal = slog.LevelError + 4
+)
+
+// fiberLogger 将 fiber/log 的调用转换为slog日志, 使现有的 log.Infof 等调用输出结构化日志
+type fiberLogger struct {
+	logger *slog.Logger
+	ctx    context.Context
+	depth  int // runtime.Callers 需要跳过的层数, 使日志记录实际调用位置
+}
+
+var _ log.AllLogger = (*fiberLogger)(nil)
+
+func (l *fiberLogger) log(level slog.Level, msg string, args ...any) {
+	if !l.logger.Enabled(l.ctx, level) {
+		return
+	}
+	var pcs [1]uintptr
+	runtime.Callers(l.depth, pcs[:])
+	record := slog.NewRecord(time.Now(), level, msg, pcs[0])
+	record.Add(args...)
+	_ = l.logger.Handler().Handle(l.ctx, record)
+}
+
+func (l *fiberLogger) Trace(v ...interface{}) { l.log(levelTrace, fmt.Sprint(v...)) }
+func (l *fiberLogger) Debug(v ...interface{}) { l.log(slog.LevelDebug, fmt.Sprint(v...)) }
+func (l *fiberLogger) Info(v ...interface{})  { l.log(slog.LevelInfo, fmt.Sprint(v...)) }
+func (l *fiberLogger) Warn(v ...interface{})  { l.log(slog.LevelWarn, fmt.Sprint(v...)) }
+func (l *fiberLogger) Error(v ...interface{}) { l.log(slog.LevelError, fmt.Sprint(v...)) }
+func (l *fiberLogger) Fatal(v ...interface{}) { l.log(levelFatal, fmt.Sprint(v...)); os.Exit(1) }
+func (l *fiberLogger) Panic(v ...interface{}) {
+	msg := fmt.Sprint(v...)
+	l.log(levelFatal, msg)
+	panic(msg)
+}
+
+func (l *fiberLogger) Tracef(format string, v ...interface{}) {
+	l.log(levelTrace, fmt.Sprintf(format, v...))
+}
+func (l *fiberLogger) Debugf(format string, v ...interface{}) {
+	l.log(slog.LevelDebug, fmt.Sprintf(format, v...))
+}
+func (l *fiberLogger) Infof(format string, v ...interface{}) {
+	l.log(slog.LevelInfo, fmt.Sprintf(format, v...))
+}
+func (l *fiberLogger) Warnf(format string, v ...interface{}) {
+	l.log(slog.LevelWarn, fmt.Sprintf(format, v...))
+}
+func (l *fiberLogger) Errorf(format string, v ...interface{}) {
+	l.log(slog.LevelError, fmt.Sprintf(format, v...))
+}
+func (l *fiberLogger) Fatalf(format string, v ...interface{}) {
+	l.log(levelFatal, fmt.Sprintf(format, v...))
+	os.Exit(1)
+}
+func (l *fiberLogger) Panicf(format string, v ...interface{}) {
+	msg := fmt.Sprintf(format, v...)
+	l.log(levelFatal, msg)
+	panic(msg)
+}
+
+func (l *fiberLogger) Tracew(msg string, keysAndValues ...interface{}) {
+	l.log(levelTrace, msg, keysAndValues...)
+}
+func (l *fiberLogger) Debugw(msg string, keysAndValues ...interface{}) {
+	l.log(slog.LevelDebug, msg, keysAndValues...)
+}
+func (l *fiberLogger) Infow(msg string, keysAndValues ...interface{}) {
+	l.log(slog.LevelInfo, msg, keysAndValues...)
+}
+func (l *fiberLogger) Warnw(msg string, keysAndValues ...interface{}) {
+	l.log(slog.LevelWarn, msg, keysAndValues...)
+}
+func (l *fiberLogger) Errorw(msg string, keysAndValues ...interface{}) {
+	l.log(slog.LevelError, msg, keysAndValues...)
+}
+func (l *fiberLogger) Fatalw(msg string, keysAndValues ...interface{}) {
+	l.log(levelFatal, msg, keysAndValues...)
+	os.Exit(1)
+}
+func (l *fiberLogger) Panicw(msg string, keysAndValues ...interface{}) {
+	l.log(levelFatal, msg, keysAndValues...)
+	panic(msg)
+}
+
+// SetLevel 日志级别由 Init 的配置决定, 忽略 fiber/log 的设置
+func (l *fiberLogger) SetLevel(log.Level) {}
+
+// SetOutput 日志输出由 Init 的配置决定, 忽略 fiber/log 的设置
+func (l *fiberLogger) SetOutput(io.Writer) {}
+
+// WithContext 返回附带请求上下文的logger, 输出的日志带上 request_id 和 trace_id
+func (l *fiberLogger) WithContext(ctx context.Context) log.CommonLogger {
+	// 直接调用返回的logger, 比通过 fiber/log 包函数调用少一层
+	return &fiberLogger{logger: l.logger, ctx: ctx, depth: 3}
+}
@@ -0,0 +1,97 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"github.com/gofiber/fiber/v2/log"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// 日志输出格式
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+type requestIDKey struct{}
+
+// Init 设置全局的slog日志, 并让 fiber/log 的调用也输出到同一个日志中
+//
+// 所有日志的字符串字段和消息都会经过 Redact 脱敏, 请求上下文中的 request_id 和 trace_id 自动附加到日志字段
+func Init(w io.Writer, level, format string) error {
+	var lv slog.Level
+	if err := lv.UnmarshalText([]byte(level)); err != nil {
+		return fmt.Errorf("invalid log level %q", level)
+	}
+	options := &slog.HandlerOptions{AddSource: true, Level: lv, ReplaceAttr: replaceAttr}
+
+	var handler slog.Handler
+	switch strings.ToLower(format) {
+	case FormatJSON, "":
+		handler = slog.NewJSONHandler(w, options)
+	case FormatText:
+		handler = slog.NewTextHandler(w, options)
+	default:
+		return fmt.Errorf("invalid log format %q", format)
+	}
+	logger := slog.New(contextHandler{handler})
+	slog.SetDefault(logger)
+	// 跳过 runtime.Callers、fiberLogger.log、fiberLogger的方法和 fiber/log 的包函数
+	log.SetLogger(&fiberLogger{logger: logger, ctx: context.Background(), depth: 4})
+	return nil
+}
+
+// replaceAttr 将trace和fatal级别输出为可读的名称, 并对其他字段脱敏
+func replaceAttr(groups []string, attr slog.Attr) slog.Attr {
+	if attr.Key == slog.LevelKey && len(groups) == 0 {
+		switch attr.Value.Any().(slog.Level) {
+		case levelTrace:
+			return slog.String(slog.LevelKey, "TRACE")
+		case levelFatal:
+			return slog.String(slog.LevelKey, "FATAL")
+		}
+		return attr
+	}
+	if attr.Key == slog.SourceKey || attr.Key == slog.TimeKey {
+		return attr
+	}
+	return redactAttr(groups, attr)
+}
+
+// WithRequestID 在上下文中保存请求id, 使用该上下文的日志自动带上 request_id 字段
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestID 返回上下文中保存的请求id
+func RequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// contextHandler 从上下文中读取 request_id 和 trace_id 附加到日志
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if requestID := RequestID(ctx); requestID != "" {
+		record.AddAttrs(slog.String("request_id", requestID))
+	}
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+		record.AddAttrs(slog.String("trace_id", spanContext.TraceID().String()))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,49 @@
+package logging
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Middleware 在请求上下文中保存请求id, 并在请求结束后输出访问日志
+//
+// 需注册在 requestid 和 tracing 中间件之后, handler通过 log.WithContext(ctx.UserContext()) 输出的日志会带上相同的 request_id
+func Middleware() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		start := time.Now()
+		requestID := ctx.GetRespHeader(fiber.HeaderXRequestID)
+		ctx.SetUserContext(WithRequestID(ctx.UserContext(), requestID))
+
+		err := ctx.Next()
+		// 错误响应由 ErrorHandler 写入, 先执行以获得最终状态码
+		if err != nil {
+			if handlerErr := ctx.App().ErrorHandler(ctx, err); handlerErr != nil {
+				ctx.Status(fiber.StatusInternalServerError)
+			}
+		}
+
+		status := ctx.Response().StatusCode()
+		level := slog.LevelInfo
+		switch {
+		case status >= fiber.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= fiber.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+		attrs := []slog.Attr{
+			slog.String("method", ctx.Method()),
+			slog.String("route", ctx.Route().Path),
+			slog.String("path", ctx.Path()),
+			slog.Int("status", status),
+			slog.Int64("duration_ms", time.Since(start).Milliseconds()),
+			slog.String("ip", ctx.IP()),
+		}
+		if err != nil {
+			attrs = append(attrs, slog.String("error", err.Error()))
+		}
+		slog.LogAttrs(ctx.UserContext(), level, "request", attrs...)
+		return nil
+	}
+}
@@ -0,0 +1,56 @@
+package logging
+
+import (
+	"log/slog"
+	"net/url"
+	"regexp"
+)
+
+// redacted 替换敏感信息的占位符
+const redacted = "***"
+
+var (
+	// sensitiveKeyReg 值需要隐藏的字段名
+	sensitiveKeyReg = regexp.MustCompile(`(?i)(password|passwd|pwd|secret|token|api[_-]?key|access[_-]?key|authorization|cookie|signature)`)
+	// sensitiveValueReg 文本中形如 password=xxx、token: xxx、"secret":"xxx" 的键值对
+	sensitiveValueReg = regexp.MustCompile(`(?i)("?\b(?:password|passwd|pwd|secret[_-]?(?:key|id)?|(?:access|refresh)?[_-]?token|api[_-]?key|access[_-]?key(?:[_-]?(?:id|secret))?|x-signature|signature|sign|authorization)"?\s*[=:]\s*)("[^"]*"|[^\s&,;"]+)`)
+	// urlUserinfoReg 地址中的 用户名:密码@
+	urlUserinfoReg = regexp.MustCompile(`(\w+://[^\s:/@]+):[^\s@/]+@`)
+	// bearerReg Authorization请求头中的令牌
+	bearerReg = regexp.MustCompile(`(?i)(bearer\s+)[\w\-.~+/]+=*`)
+)
+
+// Redact 隐藏文本中的密码、密钥和令牌
+func Redact(s string) string {
+	s = urlUserinfoReg.ReplaceAllString(s, "${1}:"+redacted+"@")
+	s = sensitiveValueReg.ReplaceAllString(s, "${1}"+redacted)
+	return bearerReg.ReplaceAllString(s, "${1}"+redacted)
+}
+
+// RedactURL 返回去掉用户信息和查询参数的地址, 用于记录媒体地址等可能携带签名的链接
+func RedactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return Redact(rawURL)
+	}
+	u.User = nil
+	u.RawQuery = ""
+	u.Fragment = ""
+	return u.String()
+}
+
+// redactAttr 作为 slog.HandlerOptions.ReplaceAttr, 隐藏敏感字段的值并对字符串值脱敏
+func redactAttr(_ []string, attr slog.Attr) slog.Attr {
+	if sensitiveKeyReg.MatchString(attr.Key) {
+		return slog.String(attr.Key, redacted)
+	}
+	switch attr.Value.Kind() {
+	case slog.KindString:
+		return slog.String(attr.Key, Redact(attr.Value.String()))
+	case slog.KindAny:
+		if err, ok := attr.Value.Any().(error); ok {
+			return slog.String(attr.Key, Redact(err.Error()))
+		}
+	}
+	return attr
+}
@@ -0,0 +1,190 @@
+package mediacache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/metrics"
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/redis/go-redis/v9"
+	"github.com/tencentyun/cos-go-sdk-v5"
+	"golang.org/x/sync/singleflight"
+)
+
+// 条目的来源, 通过 X-Cache 响应头返回
+const (
+	LevelMemory = "memory" // 内存缓存
+	LevelDisk   = "disk"   // 本地磁盘缓存
+	LevelCOS    = "cos"    // 对象存储中的热点条目
+	LevelMiss   = "miss"   // 从源站获取
+)
+
+// Config 媒体缓存配置, 容量为0时关闭对应的缓存层
+type Config struct {
+	MemorySize      int64         // 内存缓存总字节数
+	MemoryItemLimit int64         // 不超过该大小的条目放入内存, 其余放入磁盘
+	DiskDir         string        // 磁盘缓存目录
+	DiskSize        int64         // 磁盘缓存总字节数
+	TTL             time.Duration // 条目有效期, 0 表示不过期
+	PromoteHits     int           // 本实例命中次数达到该值时提升到对象存储, 0 表示不提升
+}
+
+// Cache 媒体代理的多级缓存: 内存LRU、本地磁盘和对象存储
+//
+// 依次查找内存和磁盘, 都未命中时同一个key只有一个请求去查找对象存储或回源, 其余请求等待共享结果
+type Cache struct {
+	config Config
+	memory *lru[*Entry]
+	disk   *diskCache
+	cos    *cosCache
+	group  singleflight.Group
+}
+
+// New 创建媒体缓存, cosClient 或 redis 为nil时不提升到对象存储
+func New(config Config, cosClient *cos.Client, redis redis.UniversalClient) (*Cache, error) {
+	c := &Cache{config: config}
+	if config.MemorySize > 0 {
+		c.memory = newLRU[*Entry](config.MemorySize, nil)
+	}
+	if config.DiskSize > 0 {
+		disk, err := newDiskCache(config.DiskDir, config.DiskSize)
+		if err != nil {
+			return nil, err
+		}
+		c.disk = disk
+	}
+	if config.PromoteHits > 0 && cosClient != nil && redis != nil {
+		c.cos = &cosCache{client: cosClient, redis: redis, ttl: config.TTL}
+	}
+	return c, nil
+}
+
+type result struct {
+	entry *Entry
+	level string
+}
+
+// Get 返回key对应的条目和来源, 未命中时调用 fetch 回源并写入缓存
+//
+// fetch 在多个请求间共享, 使用不随单个请求取消的上下文; fetch 返回错误时不缓存
+func (c *Cache) Get(ctx context.Context, key string, fetch func(ctx context.Context) (*Entry, error)) (*Entry, string, error) {
+	if e, level, hits, ok := c.local(key); ok {
+		metrics.ObserveMediaCache(level)
+		c.promote(ctx, key, e, hits)
+		return e, level, nil
+	}
+
+	v, err, _ := c.group.Do(key, func() (interface{}, error) {
+		ctx := context.WithoutCancel(ctx)
+		if c.cos != nil {
+			e, ok, err := c.cos.get(ctx, key)
+			if err != nil {
+				log.WithContext(ctx).Warnw("read media cache from cos failed", "error", err)
+			}
+			if ok && !e.expired(c.config.TTL) {
+				c.store(ctx, key, e)
+				return result{entry: e, level: LevelCOS}, nil
+			}
+		}
+
+		e, err := fetch(ctx)
+		if err != nil {
+			return nil, err
+		}
+		e.StoredAt = time.Now()
+		c.store(ctx, key, e)
+		return result{entry: e, level: LevelMiss}, nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	r := v.(result)
+	metrics.ObserveMediaCache(r.level)
+	return r.entry, r.level, nil
+}
+
+// local 查找内存和磁盘缓存, 返回条目、所在层和命中次数, 过期的条目删除
+func (c *Cache) local(key string) (*Entry, string, int, bool) {
+	if c.memory != nil {
+		if e, hits, ok := c.memory.get(key); ok {
+			if !e.expired(c.config.TTL) {
+				return e, LevelMemory, hits, true
+			}
+			c.memory.remove(key)
+		}
+	}
+	if c.disk != nil {
+		if e, hits, ok := c.disk.get(key); ok {
+			if !e.expired(c.config.TTL) {
+				return e, LevelDisk, hits, true
+			}
+			c.disk.remove(key)
+		}
+	}
+	return nil, "", 0, false
+}
+
+// store 按大小写入内存或磁盘, 写入失败不影响请求
+func (c *Cache) store(ctx context.Context, key string, e *Entry) {
+	size := int64(len(e.Data))
+	if c.memory != nil && size <= c.config.MemoryItemLimit {
+		c.memory.add(key, e, size)
+		return
+	}
+	if c.disk != nil {
+		if err := c.disk.put(key, e); err != nil {
+			log.WithContext(ctx).Warnw("write media cache file failed", "error", err)
+		}
+	}
+}
+
+// promote 命中次数刚达到阈值时在后台上传到对象存储
+func (c *Cache) promote(ctx context.Context, key string, e *Entry, hits int) {
+	if c.cos == nil || hits != c.config.PromoteHits {
+		return
+	}
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		if err := c.cos.put(ctx, key, e); err != nil {
+			log.WithContext(ctx).Warnw("promote media cache to cos failed", "error", err)
+		}
+	}()
+}
+
+// Key 返回媒体地址和处理参数对应的缓存key
+//
+// 地址的协议和域名转为小写, 去掉默认端口和锚点, 查询参数按名称排序, 参数顺序不同的相同地址共享缓存
+func Key(mediaURL string, params ...string) string {
+	raw := normalizeURL(mediaURL) + "|" + strings.Join(params, "|")
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+func normalizeURL(mediaURL string) string {
+	u, err := url.Parse(mediaURL)
+	if err != nil {
+		return mediaURL
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	if port := u.Port(); (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		u.Host = strings.TrimSuffix(u.Host, ":"+port)
+	}
+	u.Fragment = ""
+	u.RawFragment = ""
+	u.RawQuery = u.Query().Encode()
+	return u.String()
+}
+
+// isKey 判断文件名是否为 Key 生成的缓存key
+func isKey(name string) bool {
+	if len(name) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(name)
+	return err == nil
+}
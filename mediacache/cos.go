@@ -0,0 +1,88 @@
+package mediacache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/metrics"
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/tracing"
+	"github.com/redis/go-redis/v9"
+	"github.com/tencentyun/cos-go-sdk-v5"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	// cosKeyPrefix 热点条目在对象存储中的key前缀, 需为该前缀配置生命周期规则清理过期对象
+	cosKeyPrefix = "media-cache/"
+	// promotedKeyPrefix 已提升到对象存储的标记在Redis中的key前缀, 标记随条目有效期过期
+	promotedKeyPrefix = "mediacache:cos:"
+
+	uploadingMarker = "uploading" // 上传中, 其他实例不读取也不重复上传
+	promotedMarker  = "promoted"  // 已上传, 可以读取
+)
+
+// cosCache 对象存储缓存, 保存各实例共享的热点条目
+//
+// 是否已提升记录在Redis中, 未提升的条目不请求对象存储
+type cosCache struct {
+	client *cos.Client
+	redis  redis.UniversalClient
+	ttl    time.Duration
+}
+
+// get 读取已提升的条目
+func (c *cosCache) get(ctx context.Context, key string) (*Entry, bool, error) {
+	marker, err := c.redis.Get(ctx, promotedKeyPrefix+key).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, false, err
+	}
+	// 未提升或仍在上传中
+	if marker != promotedMarker {
+		return nil, false, nil
+	}
+
+	ctx, span := tracing.Start(ctx, "cos.GetObject", attribute.String("cos.key", cosKeyPrefix+key))
+	resp, err := c.client.Object.Get(ctx, cosKeyPrefix+key, nil)
+	if err != nil {
+		tracing.End(span, err)
+		return nil, false, err
+	}
+	defer resp.Body.Close()
+	e, err := readEntry(resp.Body)
+	tracing.End(span, err)
+	if err != nil {
+		return nil, false, err
+	}
+	return e, true, nil
+}
+
+// put 上传条目并设置提升标记, 其他实例已提升时跳过
+func (c *cosCache) put(ctx context.Context, key string, e *Entry) error {
+	var ttl time.Duration
+	if c.ttl > 0 {
+		if ttl = c.ttl - time.Since(e.StoredAt); ttl <= 0 {
+			return nil
+		}
+	}
+	// 先写入上传中标记, 避免多个实例同时上传; 上传失败时删除
+	ok, err := c.redis.SetNX(ctx, promotedKeyPrefix+key, uploadingMarker, ttl).Result()
+	if err != nil || !ok {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if err := writeEntry(&buf, e); err != nil {
+		return errors.Join(err, c.redis.Del(ctx, promotedKeyPrefix+key).Err())
+	}
+	ctx, span := tracing.Start(ctx, "cos.PutObject", attribute.String("cos.key", cosKeyPrefix+key), attribute.Int("cos.size", buf.Len()))
+	size := int64(buf.Len())
+	_, err = c.client.Object.Put(ctx, cosKeyPrefix+key, &buf, nil)
+	tracing.End(span, err)
+	if err != nil {
+		return errors.Join(err, c.redis.Del(ctx, promotedKeyPrefix+key).Err())
+	}
+	metrics.ObserveCOSUpload(size)
+	return c.redis.Set(ctx, promotedKeyPrefix+key, promotedMarker, ttl).Err()
+}
@@ -0,0 +1,127 @@
+package mediacache
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2/log"
+)
+
+// diskCache 本地磁盘缓存, 每个条目一个文件, 总大小超过容量时删除最久未访问的文件
+type diskCache struct {
+	dir   string
+	index *lru[struct{}]
+}
+
+// newDiskCache 创建磁盘缓存, 已有的缓存文件按修改时间加入索引, 重启后仍可命中
+func newDiskCache(dir string, capacity int64) (*diskCache, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	d := &diskCache{dir: dir}
+	d.index = newLRU(capacity, func(key string, _ struct{}) {
+		if err := os.Remove(d.path(key)); err != nil && !os.IsNotExist(err) {
+			log.Warnw("remove media cache file failed", "error", err)
+		}
+	})
+
+	type file struct {
+		key     string
+		size    int64
+		modTime time.Time
+	}
+	var files []file
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		// 写入中断留下的临时文件
+		if strings.HasPrefix(entry.Name(), ".tmp-") {
+			return os.Remove(path)
+		}
+		if !isKey(entry.Name()) {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		files = append(files, file{key: entry.Name(), size: info.Size(), modTime: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(files, func(a, b file) int { return a.modTime.Compare(b.modTime) })
+	for _, f := range files {
+		d.index.add(f.key, struct{}{}, f.size)
+	}
+	return d, nil
+}
+
+// path 返回条目的文件路径, 按key前两位分目录避免单个目录文件过多
+func (d *diskCache) path(key string) string {
+	return filepath.Join(d.dir, key[:2], key)
+}
+
+// get 读取条目和命中次数, 文件损坏时删除
+func (d *diskCache) get(key string) (*Entry, int, bool) {
+	_, hits, ok := d.index.get(key)
+	if !ok {
+		return nil, 0, false
+	}
+	file, err := os.Open(d.path(key))
+	if err != nil {
+		d.index.remove(key)
+		return nil, 0, false
+	}
+	defer file.Close()
+	e, err := readEntry(file)
+	if err != nil {
+		log.Warnw("read media cache file failed", "error", err)
+		d.index.remove(key)
+		return nil, 0, false
+	}
+	return e, hits, true
+}
+
+// put 先写入临时文件再重命名, 读取时不会看到写了一半的文件
+func (d *diskCache) put(key string, e *Entry) error {
+	path := d.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := writeEntry(tmp, e); err != nil {
+		tmp.Close()
+		return err
+	}
+	info, err := tmp.Stat()
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	if !d.index.add(key, struct{}{}, info.Size()) {
+		os.Remove(path)
+	}
+	return nil
+}
+
+// remove 删除条目
+func (d *diskCache) remove(key string) {
+	d.index.remove(key)
+}
@@ -0,0 +1,51 @@
+package mediacache
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Entry 缓存的媒体内容, 取出后只读, 多个请求共享同一个 Entry
+type Entry struct {
+	Header   map[string]string `json:"header"`    // 命中时还原的响应头, 如 Content-Type
+	StoredAt time.Time         `json:"stored_at"` // 从源站获取的时间
+	Data     []byte            `json:"-"`
+}
+
+// expired 判断条目是否超过有效期
+func (e *Entry) expired(ttl time.Duration) bool {
+	return ttl > 0 && time.Since(e.StoredAt) > ttl
+}
+
+// writeEntry 写入条目: 第一行为JSON格式的元数据, 之后为原始内容, 磁盘和对象存储使用相同格式
+func writeEntry(w io.Writer, e *Entry) error {
+	meta, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(append(meta, '\n')); err != nil {
+		return err
+	}
+	_, err = w.Write(e.Data)
+	return err
+}
+
+// readEntry 读取 writeEntry 写入的条目
+func readEntry(r io.Reader) (*Entry, error) {
+	reader := bufio.NewReader(r)
+	meta, err := reader.ReadBytes('\n')
+	if err != nil {
+		return nil, fmt.Errorf("read cache entry meta: %w", err)
+	}
+	e := &Entry{}
+	if err := json.Unmarshal(meta, e); err != nil {
+		return nil, fmt.Errorf("decode cache entry meta: %w", err)
+	}
+	if e.Data, err = io.ReadAll(reader); err != nil {
+		return nil, fmt.Errorf("read cache entry data: %w", err)
+	}
+	return e, nil
+}
@@ -0,0 +1,95 @@
+package mediacache
+
+import (
+	"container/list"
+	"sync"
+)
+
+// lru 按字节数限制容量的LRU, 超出容量时淘汰最久未访问的条目
+type lru[V any] struct {
+	mu       sync.Mutex
+	capacity int64
+	size     int64
+	ll       *list.List
+	items    map[string]*list.Element
+	onEvict  func(key string, value V) // 条目被淘汰或移除后调用, 替换时不调用; 调用时不持有锁
+}
+
+type lruItem[V any] struct {
+	key   string
+	value V
+	size  int64
+	hits  int // 加入后的命中次数
+}
+
+func newLRU[V any](capacity int64, onEvict func(key string, value V)) *lru[V] {
+	return &lru[V]{
+		capacity: capacity,
+		ll:       list.New(),
+		items:    make(map[string]*list.Element),
+		onEvict:  onEvict,
+	}
+}
+
+// get 返回条目和加上本次后的命中次数
+func (l *lru[V]) get(key string) (V, int, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	element, ok := l.items[key]
+	if !ok {
+		var zero V
+		return zero, 0, false
+	}
+	l.ll.MoveToFront(element)
+	item := element.Value.(*lruItem[V])
+	item.hits++
+	return item.value, item.hits, true
+}
+
+// add 加入或替换条目, 超过总容量的条目不加入
+func (l *lru[V]) add(key string, value V, size int64) bool {
+	if size > l.capacity {
+		return false
+	}
+	var evicted []*lruItem[V]
+	l.mu.Lock()
+	// 替换时不调用 onEvict, 磁盘缓存的文件此时已被新内容覆盖
+	if element, ok := l.items[key]; ok {
+		l.removeElement(element)
+	}
+	l.items[key] = l.ll.PushFront(&lruItem[V]{key: key, value: value, size: size})
+	l.size += size
+	for l.size > l.capacity {
+		evicted = append(evicted, l.removeElement(l.ll.Back()))
+	}
+	l.mu.Unlock()
+	l.evict(evicted)
+	return true
+}
+
+// remove 移除条目
+func (l *lru[V]) remove(key string) {
+	var evicted []*lruItem[V]
+	l.mu.Lock()
+	if element, ok := l.items[key]; ok {
+		evicted = append(evicted, l.removeElement(element))
+	}
+	l.mu.Unlock()
+	l.evict(evicted)
+}
+
+func (l *lru[V]) removeElement(element *list.Element) *lruItem[V] {
+	item := l.ll.Remove(element).(*lruItem[V])
+	delete(l.items, item.key)
+	l.size -= item.size
+	return item
+}
+
+func (l *lru[V]) evict(items []*lruItem[V]) {
+	if l.onEvict == nil {
+		return
+	}
+	for _, item := range items {
+		l.onEvict(item.key, item.value)
+	}
+}
@@ -0,0 +1,38 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Middleware 按路由和状态码记录请求数和耗时
+//
+// 路由使用注册时的路径(如 /api/tools/jobs/:id), 未匹配任何路由的请求记为 unmatched, 避免标签数量随URL增长
+func Middleware() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		start := time.Now()
+		err := ctx.Next()
+		// 错误响应由 ErrorHandler 写入, 先执行以获得最终状态码
+		if err != nil {
+			if handlerErr := ctx.App().ErrorHandler(ctx, err); handlerErr != nil {
+				ctx.Status(fiber.StatusInternalServerError)
+			}
+		}
+
+		// 未匹配路由时最后执行的是全局中间件, 其路径为 /
+		route, status := ctx.Route().Path, ctx.Response().StatusCode()
+		if route == "/" && status == fiber.StatusNotFound {
+			route = "unmatched"
+		}
+		ObserveHTTP(ctx.Method(), route, status, time.Since(start))
+		return nil
+	}
+}
+
+// Handler 输出Prometheus格式的指标
+func Handler() fiber.Handler {
+	return adaptor.HTTPHandler(promhttp.Handler())
+}
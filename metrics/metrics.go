@@ -0,0 +1,110 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "HTTP请求数",
+	}, []string{"method", "route", "status"})
+	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "HTTP请求处理耗时",
+		Buckets: []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"method", "route", "status"})
+
+	parseRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "parse_requests_total",
+		Help: "分享链接解析次数, result 为 success 或失败分类",
+	}, []string{"source", "result"})
+	parseDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "parse_duration_seconds",
+		Help:    "分享链接解析耗时",
+		Buckets: []float64{0.1, 0.25, 0.5, 1, 2, 5, 10, 30},
+	}, []string{"source"})
+
+	upstreamRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "upstream_requests_total",
+		Help: "出站请求数, 每次重试单独计数, status 为HTTP状态码、error 或 circuit_open",
+	}, []string{"host", "status"})
+	upstreamDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "upstream_request_duration_seconds",
+		Help:    "出站请求到收到响应头的耗时",
+		Buckets: []float64{0.05, 0.1, 0.25, 0.5, 1, 2, 5, 10, 30},
+	}, []string{"host"})
+	upstreamBytes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "upstream_response_bytes_total",
+		Help: "从出站请求读取的响应体字节数",
+	}, []string{"host"})
+
+	ffmpegDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "ffmpeg_duration_seconds",
+		Help:    "ffmpeg执行耗时",
+		Buckets: []float64{0.05, 0.1, 0.5, 1, 5, 15, 30, 60, 120, 300},
+	}, []string{"operation"})
+	ffmpegFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ffmpeg_failures_total",
+		Help: "ffmpeg执行失败次数",
+	}, []string{"operation"})
+
+	mediaCacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "media_cache_requests_total",
+		Help: "媒体代理缓存查找次数, level 为命中的缓存层(memory、disk、cos)或 miss",
+	}, []string{"level"})
+
+	cosUploadSize = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "cos_upload_size_bytes",
+		Help:    "上传到对象存储的文件大小",
+		Buckets: prometheus.ExponentialBuckets(16*1024, 4, 9), // 16KB ~ 1GB
+	})
+)
+
+// ObserveHTTP 记录一次HTTP请求
+func ObserveHTTP(method, route string, status int, duration time.Duration) {
+	code := strconv.Itoa(status)
+	httpRequests.WithLabelValues(method, route, code).Inc()
+	httpDuration.WithLabelValues(method, route, code).Observe(duration.Seconds())
+}
+
+// ObserveParse 记录一次分享链接解析, result 为 success 或失败分类
+func ObserveParse(source, result string, duration time.Duration) {
+	parseRequests.WithLabelValues(source, result).Inc()
+	parseDuration.WithLabelValues(source).Observe(duration.Seconds())
+}
+
+// ObserveUpstream 记录一次出站请求, status 为HTTP状态码、error 或 circuit_open
+func ObserveUpstream(host, status string, duration time.Duration) {
+	upstreamRequests.WithLabelValues(host, status).Inc()
+	if duration > 0 {
+		upstreamDuration.WithLabelValues(host).Observe(duration.Seconds())
+	}
+}
+
+// AddUpstreamBytes 累加出站请求读取的响应体字节数
+func AddUpstreamBytes(host string, n int) {
+	upstreamBytes.WithLabelValues(host).Add(float64(n))
+}
+
+// ObserveFFmpeg 记录一次ffmpeg执行, 在执行完成后调用
+func ObserveFFmpeg(operation string, start time.Time, err error) {
+	ffmpegDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+	if err != nil {
+		ffmpegFailures.WithLabelValues(operation).Inc()
+	}
+}
+
+// ObserveCOSUpload 记录一次对象存储上传的文件大小
+func ObserveCOSUpload(size int64) {
+	cosUploadSize.Observe(float64(size))
+}
+
+// ObserveMediaCache 记录一次媒体代理缓存查找, level 为命中的缓存层或 miss
+func ObserveMediaCache(level string) {
+	mediaCacheRequests.WithLabelValues(level).Inc()
+}
@@ -0,0 +1,52 @@
+package metrics
+
+import (
+	"database/sql"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/redis/go-redis/v9"
+)
+
+// RegisterDB 注册数据库连接池指标(go_sql_*), name 作为 db_name 标签
+func RegisterDB(db *sql.DB, name string) {
+	prometheus.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
+
+// RegisterRedis 注册Redis连接池指标
+func RegisterRedis(client redis.UniversalClient) {
+	prometheus.MustRegister(&redisPoolCollector{client: client})
+}
+
+var (
+	redisHitsDesc     = prometheus.NewDesc("redis_pool_hits_total", "从连接池获取到空闲连接的次数", nil, nil)
+	redisMissesDesc   = prometheus.NewDesc("redis_pool_misses_total", "连接池没有空闲连接需要新建的次数", nil, nil)
+	redisTimeoutsDesc = prometheus.NewDesc("redis_pool_timeouts_total", "等待连接池连接超时的次数", nil, nil)
+	redisTotalDesc    = prometheus.NewDesc("redis_pool_connections", "连接池中的连接数", nil, nil)
+	redisIdleDesc     = prometheus.NewDesc("redis_pool_idle_connections", "连接池中的空闲连接数", nil, nil)
+	redisStaleDesc    = prometheus.NewDesc("redis_pool_stale_connections_total", "从连接池移除的失效连接数", nil, nil)
+)
+
+// redisPoolCollector 在抓取时读取Redis客户端的连接池统计, 集群模式为所有节点的合计
+type redisPoolCollector struct {
+	client redis.UniversalClient
+}
+
+func (c *redisPoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- redisHitsDesc
+	ch <- redisMissesDesc
+	ch <- redisTimeoutsDesc
+	ch <- redisTotalDesc
+	ch <- redisIdleDesc
+	ch <- redisStaleDesc
+}
+
+func (c *redisPoolCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.client.PoolStats()
+	ch <- prometheus.MustNewConstMetric(redisHitsDesc, prometheus.CounterValue, float64(stats.Hits))
+	ch <- prometheus.MustNewConstMetric(redisMissesDesc, prometheus.CounterValue, float64(stats.Misses))
+	ch <- prometheus.MustNewConstMetric(redisTimeoutsDesc, prometheus.CounterValue, float64(stats.Timeouts))
+	ch <- prometheus.MustNewConstMetric(redisTotalDesc, prometheus.GaugeValue, float64(stats.TotalConns))
+	ch <- prometheus.MustNewConstMetric(redisIdleDesc, prometheus.GaugeValue, float64(stats.IdleConns))
+	ch <- prometheus.MustNewConstMetric(redisStaleDesc, prometheus.CounterValue, float64(stats.StaleConns))
+}
@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// ParseHealthStatus 平台解析健康状态
+type ParseHealthStatus string
+
+const (
+	ParseHealthUnknown ParseHealthStatus = "unknown" // 未配置探测链接或尚未探测
+	ParseHealthUp      ParseHealthStatus = "up"      // 最近一次探测成功
+	ParseHealthDown    ParseHealthStatus = "down"    // 最近一次探测失败
+)
+
+// ParseHealth 单个平台的解析探测结果
+type ParseHealth struct {
+	Source              string            `json:"source"`
+	Status              ParseHealthStatus `json:"status"`
+	CanaryUrl           string            `json:"canary_url,omitempty"`      // 探测使用的分享链接
+	LatencyMs           int64             `json:"latency_ms"`                // 最近一次探测耗时
+	Error               string            `json:"error,omitempty"`           // 最近一次探测失败原因
+	ConsecutiveFailures int               `json:"consecutive_failures"`      // 连续失败次数
+	LastCheckedAt       *time.Time        `json:"last_checked_at,omitempty"` // 最近一次探测时间
+	LastSuccessAt       *time.Time        `json:"last_success_at,omitempty"` // 最近一次成功时间
+}
+
+// PlatformInfo 支持的平台及其分享链接域名
+type PlatformInfo struct {
+	Source   string   `json:"source"`
+	Domains  []string `json:"domains"`
+	IdParser bool     `json:"id_parser"` // 是否支持按视频id解析
+}
+
+// CircuitBreaker 出站请求按域名熔断的状态
+type CircuitBreaker struct {
+	Host                string     `json:"host"`
+	State               string     `json:"state"` // closed、open 或 half_open
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	OpenedAt            *time.Time `json:"opened_at,omitempty"` // 最近一次熔断时间
+	Trips               int64      `json:"trips"`               // 累计熔断次数
+}
+
+// ParseStatus 平台解析健康状态查询结果
+type ParseStatus struct {
+	Health    []ParseHealth    `json:"health"`
+	Platforms []PlatformInfo   `json:"platforms"`
+	Breakers  []CircuitBreaker `json:"breakers"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// JobStatus 异步任务状态
+type JobStatus string
+
+const (
+	JobStatusPending   JobStatus = "pending"   // 排队中
+	JobStatusRunning   JobStatus = "running"   // 执行中
+	JobStatusSucceeded JobStatus = "succeeded" // 执行成功
+	JobStatusFailed    JobStatus = "failed"    // 执行失败
+)
+
+// 任务类型
+const (
+	JobTypeOfficeToPDF = "office_to_pdf" // Office文档转PDF
+	JobTypeTranscode   = "transcode"     // 远程视频转码为MP4
+)
+
+// Job 异步任务
+type Job struct {
+	ID        string            `json:"id"`
+	Type      string            `json:"type"`
+	Status    JobStatus         `json:"status"`
+	Params    map[string]string `json:"params"`               // 任务参数
+	ResultKey string            `json:"result_key,omitempty"` // 结果文件key
+	Error     string            `json:"error,omitempty"`      // 失败原因
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
@@ -1,13 +1,15 @@
 package models
 
+import "context"
+
 // videoShareUrlParser 根据视频分享地址解析
 type VideoShareUrlParser interface {
-	ParseShareUrl(shareUrl string) (*VideoParseInfo, error)
+	ParseShareUrl(ctx context.Context, shareUrl string) (*VideoParseInfo, error)
 }
 
 // videoIdParser 根据视频ID解析
 type VideoIdParser interface {
-	ParseVideoID(videoId string) (*VideoParseInfo, error)
+	ParseVideoID(ctx context.Context, videoId string) (*VideoParseInfo, error)
 }
 
 // VideoParseInfo 视频解析信息
@@ -17,11 +19,32 @@ type VideoParseInfo struct {
 		Name   string `json:"name"`   // 作者名称
 		Avatar string `json:"avatar"` // 作者头像
 	} `json:"author"`
-	Title    string   `json:"title"`     // 描述
-	VideoUrl string   `json:"video_url"` // 视频播放地址
+	Title    string `json:"title"`     // 描述
+	VideoUrl string `json:"video_url"` // 视频播放地址
+	// 音频地址, 仅音视频分离(DASH)的平台返回, 需要与 VideoUrl 合并后播放
+	AudioUrl string   `json:"audio_url,omitempty"`
 	MusicUrl string   `json:"music_url"` // 音乐播放地址
 	CoverUrl string   `json:"cover_url"` // 视频封面地址
 	Images   []string `json:"images"`    // 图集图片地址列表
+	// 实况图片的视频片段, 没有实况图片时为空
+	LivePhotos []LivePhoto `json:"live_photos,omitempty"`
+	// 多P视频的分P列表, 单P视频为空
+	Parts []VideoPart `json:"parts,omitempty"`
+}
+
+// VideoPart 多P视频的单个分P
+type VideoPart struct {
+	Page     int    `json:"page"`      // 分P序号, 从1开始
+	Title    string `json:"title"`     // 分P标题
+	Duration int    `json:"duration"`  // 时长(秒)
+	VideoUrl string `json:"video_url"` // 视频流地址, 只有请求的分P返回, 其他分P通过带 p 参数的分享链接获取
+	AudioUrl string `json:"audio_url"` // 音频流地址, 同 VideoUrl
+}
+
+// LivePhoto 实况图片的视频片段
+type LivePhoto struct {
+	Index    int    `json:"index"`     // 对应 Images 中的下标
+	VideoUrl string `json:"video_url"` // 视频片段地址
 }
 
 // BatchParseItem 批量解析时, 单条解析格式
@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+// Setting 运行时配置项, Value 为该配置项的JSON值
+type Setting struct {
+	Key       string    `json:"key" gorm:"primaryKey;size:100"`
+	Value     string    `json:"value" gorm:"type:jsonb;not null"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
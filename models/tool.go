@@ -1,5 +1,7 @@
 package models
 
+import "context"
+
 // 分类结构
 type Category struct {
 	Base
@@ -56,17 +58,7 @@ func (t *Tool) ToResponse() map[string]interface{} {
 	}
 }
 
-// 工具列表响应结构 - 仅用于API响应
-type ToolsResponse struct {
-	Code    int    `json:"code"`
-	Message string `json:"message"`
-	Data    struct {
-		Categories []Category `json:"categories"`
-		Tools      []Tool     `json:"tools"`
-	} `json:"data"`
-}
-
 // 工具仓库接口
 type NewToolRepository interface {
-	GetAllTools() ([]*Tool, error)
+	GetAllTools(ctx context.Context) ([]*Tool, error)
 }
@@ -0,0 +1,48 @@
+package ratelimit
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/response"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/redis/go-redis/v9"
+)
+
+// window 限流的时间窗口
+const window = time.Minute
+
+// Middleware 按客户端IP限制每分钟的请求数, 计数保存在Redis中由所有实例共享
+//
+// limit 在每个请求时调用, 返回0时不限制, 使限流阈值可以在运行时修改; Redis不可用时不限流
+func Middleware(rdb redis.UniversalClient, limit func() int) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		perMinute := limit()
+		if perMinute <= 0 {
+			return ctx.Next()
+		}
+
+		now := time.Now()
+		windowStart := now.Truncate(window)
+		key := "ratelimit:" + ctx.IP() + ":" + strconv.FormatInt(windowStart.Unix(), 10)
+
+		pipe := rdb.TxPipeline()
+		incr := pipe.Incr(ctx.UserContext(), key)
+		pipe.Expire(ctx.UserContext(), key, window)
+		if _, err := pipe.Exec(ctx.UserContext()); err != nil {
+			log.WithContext(ctx.UserContext()).Warnw("rate limit check failed", "error", err)
+			return ctx.Next()
+		}
+
+		count := int(incr.Val())
+		ctx.Set("X-RateLimit-Limit", strconv.Itoa(perMinute))
+		ctx.Set("X-RateLimit-Remaining", strconv.Itoa(max(0, perMinute-count)))
+		if count > perMinute {
+			retryAfter := windowStart.Add(window).Sub(now)
+			ctx.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(retryAfter.Seconds())+1))
+			return response.NewError(fiber.StatusTooManyRequests, "", "请求过于频繁, 请稍后重试")
+		}
+		return ctx.Next()
+	}
+}
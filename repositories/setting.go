@@ -0,0 +1,37 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/models"
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+type SettingRepository struct {
+	db *gorm.DB
+}
+
+// ListSettings 从主库读取全部配置项, 修改后重新加载时不受副本延迟影响
+func (r *SettingRepository) ListSettings(ctx context.Context) ([]*models.Setting, error) {
+	settings := []*models.Setting{}
+	if err := r.db.WithContext(ctx).Clauses(dbresolver.Write).Find(&settings).Error; err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+// SaveSetting 新增或覆盖配置项
+func (r *SettingRepository) SaveSetting(ctx context.Context, setting *models.Setting) error {
+	return r.db.WithContext(ctx).Save(setting).Error
+}
+
+func (r *SettingRepository) DeleteSetting(ctx context.Context, key string) error {
+	return r.db.WithContext(ctx).Delete(&models.Setting{}, "key = ?", key).Error
+}
+
+func NewSettingRepository(db *gorm.DB) *SettingRepository {
+	return &SettingRepository{
+		db: db,
+	}
+}
@@ -1,6 +1,8 @@
 package repositories
 
 import (
+	"context"
+
 	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/models"
 	"gorm.io/gorm"
 )
@@ -9,15 +11,15 @@ type ToolRepository struct {
 	db *gorm.DB
 }
 
-func (r *ToolRepository) GetAllTools() ([]*models.Tool, error) {
+func (r *ToolRepository) GetAllTools(ctx context.Context) ([]*models.Tool, error) {
 	tools := []*models.Tool{}
-	if err := r.db.Model(&models.Tool{}).Preload("Steps").Preload("Categories").Find(&tools).Error; err != nil {
+	if err := r.db.WithContext(ctx).Model(&models.Tool{}).Preload("Steps").Preload("Categories").Find(&tools).Error; err != nil {
 		return nil, err
 	}
 	return tools, nil
 }
-func (r *ToolRepository) CreateTool(tool *models.Tool) error {
-	return r.db.Create(tool).Error
+func (r *ToolRepository) CreateTool(ctx context.Context, tool *models.Tool) error {
+	return r.db.WithContext(ctx).Create(tool).Error
 }
 func NewToolRepository(db *gorm.DB) *ToolRepository {
 	return &ToolRepository{
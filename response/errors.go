@@ -0,0 +1,126 @@
+package response
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/service"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+)
+
+// 错误码, 客户端根据错误码展示提示, 不应依赖 message 文本
+const (
+	CodeUnsupportedPlatform = "unsupported_platform"
+	CodeInvalidLink         = "invalid_link"
+	CodeContentUnavailable  = "content_unavailable"
+	CodeParseFailed         = "parse_failed"
+	CodeUpstreamBlocked     = "upstream_blocked"
+	CodeUpstreamTimeout     = "upstream_timeout"
+	CodeUpstreamUnavailable = "upstream_unavailable"
+	CodeRateLimited         = "rate_limited"
+	CodeMediaTooLarge       = "media_too_large"
+	CodeMediaProcessing     = "media_processing_failed"
+	CodeFeatureDisabled     = "feature_disabled"
+	CodePlatformDisabled    = "platform_disabled"
+	CodeJobQueueFull        = "job_queue_full"
+	CodeObjectNotFound      = "object_not_found"
+	CodeInvalidPageRange    = "invalid_page_range"
+	CodeInternal            = "internal_error"
+)
+
+// Error 携带HTTP状态码、错误码和提示的错误, handler直接返回后由 ErrorHandler 转换为统一响应
+type Error struct {
+	Status  int
+	Code    string
+	Message string
+	Err     error // 原始错误, 仅用于日志
+}
+
+// NewError 创建错误, code 为空时由HTTP状态码推导
+func NewError(status int, code, message string) *Error {
+	if code == "" {
+		code = codeForStatus(status)
+	}
+	return &Error{Status: status, Code: code, Message: message}
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// classifiedErrors 解析和代理错误分类对应的响应
+var classifiedErrors = []struct {
+	kind error
+	*Error
+}{
+	{service.ErrUnsupportedPlatform, NewError(fiber.StatusBadRequest, CodeUnsupportedPlatform, "暂不支持该平台的链接")},
+	{service.ErrInvalidLink, NewError(fiber.StatusBadRequest, CodeInvalidLink, "链接无效或已过期, 请重新复制分享链接")},
+	{service.ErrContentUnavailable, NewError(fiber.StatusNotFound, CodeContentUnavailable, "作品已被删除或设为私密")},
+	{service.ErrParseFailed, NewError(fiber.StatusUnprocessableEntity, CodeParseFailed, "暂时无法解析该作品, 请稍后重试")},
+	{service.ErrObjectNotFound, NewError(fiber.StatusNotFound, CodeObjectNotFound, "文件不存在或已过期, 请重新上传")},
+	{service.ErrInvalidPageRange, NewError(fiber.StatusBadRequest, CodeInvalidPageRange, "页码范围无效")},
+	{service.ErrMediaTooLarge, NewError(fiber.StatusUnprocessableEntity, CodeMediaTooLarge, "文件过大, 无法处理")},
+	{service.ErrMediaProcessing, NewError(fiber.StatusUnprocessableEntity, CodeMediaProcessing, "媒体文件处理失败")},
+	{service.ErrPlatformDisabled, NewError(fiber.StatusServiceUnavailable, CodePlatformDisabled, "该平台暂时无法使用, 请稍后再试")},
+	{service.ErrJobQueueFull, NewError(fiber.StatusServiceUnavailable, CodeJobQueueFull, "任务繁忙, 请稍后重试")},
+	{service.ErrUpstreamUnavailable, NewError(fiber.StatusServiceUnavailable, CodeUpstreamUnavailable, "平台暂时不可用, 请稍后重试")},
+	{service.ErrUpstreamTimeout, NewError(fiber.StatusGatewayTimeout, CodeUpstreamTimeout, "平台响应超时, 请稍后重试")},
+	{service.ErrRateLimited, NewError(fiber.StatusBadGateway, CodeRateLimited, "平台访问过于频繁, 请稍后重试")},
+	{service.ErrUpstreamBlocked, NewError(fiber.StatusBadGateway, CodeUpstreamBlocked, "平台暂时无法访问, 请稍后重试")},
+}
+
+// classify 将返回的错误转换为响应错误, 未分类的错误视为服务器内部错误
+func classify(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	var fe *fiber.Error
+	if errors.As(err, &fe) {
+		return NewError(fe.Code, "", fe.Message)
+	}
+	for _, c := range classifiedErrors {
+		if errors.Is(err, c.kind) {
+			return c.Error
+		}
+	}
+	if service.IsTimeout(err) {
+		return NewError(fiber.StatusGatewayTimeout, CodeUpstreamTimeout, "平台响应超时, 请稍后重试")
+	}
+	return NewError(fiber.StatusInternalServerError, CodeInternal, "服务器内部错误")
+}
+
+// ErrorHandler 作为 fiber.Config.ErrorHandler, 将handler返回的错误转换为统一响应
+func ErrorHandler(ctx *fiber.Ctx, err error) error {
+	e := classify(err)
+	if e.Status >= fiber.StatusInternalServerError {
+		log.WithContext(ctx.UserContext()).Errorw("request failed",
+			"method", ctx.Method(),
+			"route", ctx.Route().Path,
+			"status", e.Status,
+			"error", err,
+		)
+	}
+	return write(ctx, e.Status, e.Code, e.Message)
+}
+
+// codeForStatus 由HTTP状态码推导错误码, 如 404 => not_found
+func codeForStatus(status int) string {
+	if status == fiber.StatusInternalServerError {
+		return CodeInternal
+	}
+	text := http.StatusText(status)
+	if text == "" {
+		return CodeInternal
+	}
+	return strings.ReplaceAll(strings.ToLower(text), " ", "_")
+}
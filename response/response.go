@@ -0,0 +1,70 @@
+package response
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
+)
+
+// 响应状态: fail 为请求参数等客户端错误, error 为服务端错误
+const (
+	StatusSuccess = "success"
+	StatusFail    = "fail"
+	StatusError   = "error"
+)
+
+// Response 统一响应结构
+type Response struct {
+	Status    string      `json:"status" example:"success"`
+	Code      string      `json:"code,omitempty" example:"invalid_link"` // 错误码, 仅失败时返回
+	Message   string      `json:"message" example:"success"`
+	Data      interface{} `json:"data,omitempty"`
+	RequestID string      `json:"request_id,omitempty"` // 请求ID, 仅失败时返回, 用于排查日志
+}
+
+// KeyData 返回单个文件key
+type KeyData struct {
+	Key string `json:"key" example:"20240101/120000-result.pdf"`
+}
+
+// KeysData 返回多个文件key
+type KeysData struct {
+	Keys []string `json:"keys"`
+}
+
+// Success 返回成功响应
+func Success(ctx *fiber.Ctx, message string, data interface{}) error {
+	return SuccessWithStatus(ctx, fiber.StatusOK, message, data)
+}
+
+// SuccessWithStatus 使用指定状态码返回成功响应, 如创建异步任务时的 202
+func SuccessWithStatus(ctx *fiber.Ctx, status int, message string, data interface{}) error {
+	return ctx.Status(status).JSON(Response{
+		Status:  StatusSuccess,
+		Message: message,
+		Data:    data,
+	})
+}
+
+// Fail 返回失败响应, 错误码由HTTP状态码推导
+func Fail(ctx *fiber.Ctx, status int, message string) error {
+	return write(ctx, status, codeForStatus(status), message)
+}
+
+// RequestID 返回 requestid 中间件生成的请求ID
+func RequestID(ctx *fiber.Ctx) string {
+	id, _ := ctx.Locals(requestid.ConfigDefault.ContextKey).(string)
+	return id
+}
+
+func write(ctx *fiber.Ctx, status int, code, message string) error {
+	respStatus := StatusFail
+	if status >= fiber.StatusInternalServerError {
+		respStatus = StatusError
+	}
+	return ctx.Status(status).JSON(Response{
+		Status:    respStatus,
+		Code:      code,
+		Message:   message,
+		RequestID: RequestID(ctx),
+	})
+}
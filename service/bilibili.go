@@ -0,0 +1,226 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/models"
+	"github.com/go-resty/resty/v2"
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/tidwall/gjson"
+)
+
+var (
+	// biliBiliBvidReg BV号
+	biliBiliBvidReg = regexp.MustCompile(`BV[0-9A-Za-z]{10}`)
+	// biliBiliAidReg av号
+	biliBiliAidReg = regexp.MustCompile(`(?i)(?:^|/)av(\d+)`)
+)
+
+const (
+	biliBiliViewApi    = "https://api.bilibili.com/x/web-interface/view"
+	biliBiliPlayUrlApi = "https://api.bilibili.com/x/player/playurl"
+	biliBiliReferer    = "https://www.bilibili.com/"
+
+	// biliBiliFnvalDash 请求DASH格式的音视频分离流
+	biliBiliFnvalDash = "16"
+	// biliBiliCodecAVC H.264编码, 兼容性最好, 优先选择
+	biliBiliCodecAVC = 7
+)
+
+type biliBili struct{}
+
+// biliBiliStream 单个分P的DASH音视频流地址
+type biliBiliStream struct {
+	videoUrl string
+	audioUrl string
+}
+
+// ParseShareUrl 解析B站分享链接, b23.tv 短链会先跟随跳转
+func (b biliBili) ParseShareUrl(ctx context.Context, shareUrl string) (*models.VideoParseInfo, error) {
+	videoUrl := shareUrl
+	if u, err := url.Parse(shareUrl); err == nil && u.Hostname() == "b23.tv" {
+		location, err := redirectLocation(ctx, SourceBiliBili, shareUrl)
+		if err != nil {
+			return nil, fmt.Errorf("bilibili short url redirect: %w", err)
+		}
+		videoUrl = location
+	}
+
+	videoId := biliBiliBvidReg.FindString(videoUrl)
+	if videoId == "" {
+		if findRes := biliBiliAidReg.FindStringSubmatch(videoUrl); len(findRes) == 2 {
+			videoId = "av" + findRes[1]
+		}
+	}
+	if videoId == "" {
+		return nil, fmt.Errorf("%w: bilibili parse video id from url: %s", ErrInvalidLink, videoUrl)
+	}
+
+	// 分享链接中的 p 参数指定默认返回的分P
+	page := 1
+	if u, err := url.Parse(videoUrl); err == nil {
+		if p, err := strconv.Atoi(u.Query().Get("p")); err == nil && p > 0 {
+			page = p
+		}
+	}
+	return b.parse(ctx, videoId, page)
+}
+
+// ParseVideoID 根据BV号或av号(如 BV1xx411c7mD、av170001、170001)解析
+func (b biliBili) ParseVideoID(ctx context.Context, videoId string) (*models.VideoParseInfo, error) {
+	return b.parse(ctx, videoId, 1)
+}
+
+// parse 获取视频信息和分P列表, 只获取 page 指定分P的DASH流地址, 其他分P通过带 p 参数的分享链接单独解析
+func (b biliBili) parse(ctx context.Context, videoId string, page int) (*models.VideoParseInfo, error) {
+	idParams, err := b.idParams(videoId)
+	if err != nil {
+		return nil, err
+	}
+
+	client := newParseClient()
+	res, err := client.R().
+		SetContext(ctx).
+		SetHeader(HttpHeaderUserAgent, userAgent(SourceBiliBili, DesktopUserAgent)).
+		SetHeader(HttpHeaderReferer, biliBiliReferer).
+		SetQueryParams(idParams).
+		Get(biliBiliViewApi)
+	if err != nil {
+		return nil, UpstreamRequestError("bilibili request view api", err)
+	}
+	data, err := b.apiData(res)
+	if err != nil {
+		return nil, err
+	}
+
+	bvid := data.Get("bvid").String()
+	parseInfo := &models.VideoParseInfo{
+		Title:    data.Get("title").String(),
+		CoverUrl: strings.Replace(data.Get("pic").String(), "http://", "https://", 1),
+	}
+	parseInfo.Author.Uid = data.Get("owner.mid").String()
+	parseInfo.Author.Name = data.Get("owner.name").String()
+	parseInfo.Author.Avatar = data.Get("owner.face").String()
+
+	pages := data.Get("pages").Array()
+	if len(pages) == 0 {
+		return nil, fmt.Errorf("%w: bilibili video has no pages", ErrContentUnavailable)
+	}
+	current := -1
+	parseInfo.Parts = make([]models.VideoPart, 0, len(pages))
+	for _, p := range pages {
+		part := models.VideoPart{
+			Page:     int(p.Get("page").Int()),
+			Title:    p.Get("part").String(),
+			Duration: int(p.Get("duration").Int()),
+		}
+		if part.Page == page {
+			current = len(parseInfo.Parts)
+		}
+		parseInfo.Parts = append(parseInfo.Parts, part)
+	}
+	if current < 0 {
+		return nil, fmt.Errorf("%w: bilibili video has no page %d", ErrInvalidLink, page)
+	}
+
+	// 指定分P获取失败时直接返回错误, 不用其他分P代替
+	stream, err := b.playUrl(ctx, bvid, pages[current].Get("cid").String())
+	if err != nil {
+		log.WithContext(ctx).Warnw("bilibili parse play url failed", "bvid", bvid, "page", page, "error", err)
+		return nil, err
+	}
+	parseInfo.VideoUrl, parseInfo.AudioUrl = stream.videoUrl, stream.audioUrl
+	parseInfo.Parts[current].VideoUrl, parseInfo.Parts[current].AudioUrl = stream.videoUrl, stream.audioUrl
+	// 单P视频不返回分P列表
+	if len(parseInfo.Parts) == 1 {
+		parseInfo.Parts = nil
+	}
+	return parseInfo, nil
+}
+
+// playUrl 获取单个分P的DASH流地址, 视频优先选择H.264编码中画质最高的流
+func (b biliBili) playUrl(ctx context.Context, bvid, cid string) (biliBiliStream, error) {
+	client := newParseClient()
+	res, err := client.R().
+		SetContext(ctx).
+		SetHeader(HttpHeaderUserAgent, userAgent(SourceBiliBili, DesktopUserAgent)).
+		SetHeader(HttpHeaderReferer, biliBiliReferer).
+		SetQueryParams(map[string]string{
+			"bvid":  bvid,
+			"cid":   cid,
+			"fnval": biliBiliFnvalDash,
+			"fourk": "1",
+		}).
+		Get(biliBiliPlayUrlApi)
+	if err != nil {
+		return biliBiliStream{}, UpstreamRequestError("bilibili request playurl api", err)
+	}
+	data, err := b.apiData(res)
+	if err != nil {
+		return biliBiliStream{}, err
+	}
+
+	var video, audio gjson.Result
+	for _, v := range data.Get("dash.video").Array() {
+		if !video.Exists() ||
+			(v.Get("codecid").Int() == biliBiliCodecAVC && video.Get("codecid").Int() != biliBiliCodecAVC) ||
+			(v.Get("codecid").Int() == video.Get("codecid").Int() && v.Get("id").Int() > video.Get("id").Int()) {
+			video = v
+		}
+	}
+	for _, a := range data.Get("dash.audio").Array() {
+		if !audio.Exists() || a.Get("bandwidth").Int() > audio.Get("bandwidth").Int() {
+			audio = a
+		}
+	}
+	if !video.Exists() {
+		return biliBiliStream{}, fmt.Errorf("%w: bilibili playurl has no dash video", ErrParseFailed)
+	}
+	return biliBiliStream{
+		videoUrl: firstNonEmpty(video.Get("baseUrl").String(), video.Get("base_url").String()),
+		audioUrl: firstNonEmpty(audio.Get("baseUrl").String(), audio.Get("base_url").String()),
+	}, nil
+}
+
+// biliBiliCodeError 根据接口返回码归类错误
+func biliBiliCodeError(code int64) error {
+	switch code {
+	case -400:
+		return ErrInvalidLink
+	case -404, 62002, 62004, 62012: // 稿件不存在、不可见、审核中、仅UP主可见
+		return ErrContentUnavailable
+	case -509, -799: // 请求过于频繁
+		return ErrRateLimited
+	default: // -412 等风控拦截
+		return ErrUpstreamBlocked
+	}
+}
+
+// idParams 将BV号或av号转换为接口参数
+func (b biliBili) idParams(videoId string) (map[string]string, error) {
+	if bvid := biliBiliBvidReg.FindString(videoId); bvid != "" {
+		return map[string]string{"bvid": bvid}, nil
+	}
+	aid := strings.TrimPrefix(strings.ToLower(videoId), "av")
+	if _, err := strconv.ParseUint(aid, 10, 64); err != nil {
+		return nil, fmt.Errorf("%w: invalid bilibili video id: %s", ErrInvalidLink, videoId)
+	}
+	return map[string]string{"aid": aid}, nil
+}
+
+// apiData 校验接口返回码并返回data字段
+func (b biliBili) apiData(res *resty.Response) (gjson.Result, error) {
+	if res.StatusCode() != 200 {
+		return gjson.Result{}, UpstreamStatusError("bilibili api", res.StatusCode())
+	}
+	body := gjson.ParseBytes(res.Body())
+	if code := body.Get("code").Int(); code != 0 {
+		return gjson.Result{}, fmt.Errorf("%w: bilibili api error code %d: %s", biliBiliCodeError(code), code, body.Get("message").String())
+	}
+	return body.Get("data"), nil
+}
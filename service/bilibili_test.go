@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+// bilibiliPlayUrl 校验请求的分P并返回 fixture 中的DASH流
+func bilibiliPlayUrl(t *testing.T, wantCid, fixture string) http.HandlerFunc {
+	t.Helper()
+	serve := serveFixture(t, fixture)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("bvid") != "BV1GJ411x7h7" || r.FormValue("cid") != wantCid {
+			t.Errorf("playurl query = %s, want bvid=BV1GJ411x7h7 cid=%s", r.URL.RawQuery, wantCid)
+		}
+		serve(w, r)
+	}
+}
+
+func TestBiliBiliParseShareUrlSelectsPage(t *testing.T) {
+	fakePlatforms(t, map[string]http.HandlerFunc{
+		"api.bilibili.com/x/web-interface/view": serveFixture(t, "bilibili_view.json"),
+		"api.bilibili.com/x/player/playurl":     bilibiliPlayUrl(t, "137649200", "bilibili_playurl.json"),
+	})
+
+	parseInfo, err := biliBili{}.ParseShareUrl(context.Background(), "https://www.bilibili.com/video/BV1GJ411x7h7/?p=2&share_source=copy_web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parseInfo.Title != "【合集】城市骑行记录" {
+		t.Errorf("title = %q", parseInfo.Title)
+	}
+	if parseInfo.CoverUrl != "https://i0.hdslb.com/bfs/archive/cover.jpg" {
+		t.Errorf("cover url = %q", parseInfo.CoverUrl)
+	}
+	if len(parseInfo.Parts) != 3 {
+		t.Fatalf("parts = %d, want 3", len(parseInfo.Parts))
+	}
+	// 只有指定的分P带流地址
+	for i, part := range parseInfo.Parts {
+		if (i == 1) != (part.VideoUrl != "") {
+			t.Errorf("part %d video url = %q", part.Page, part.VideoUrl)
+		}
+	}
+	if parseInfo.Parts[1].Title != "第二天 山路" || parseInfo.Parts[1].VideoUrl != parseInfo.VideoUrl {
+		t.Errorf("part 2 = %+v", parseInfo.Parts[1])
+	}
+}
+
+func TestBiliBiliParseShareUrlUnknownPage(t *testing.T) {
+	fakePlatforms(t, map[string]http.HandlerFunc{
+		"api.bilibili.com/x/web-interface/view": serveFixture(t, "bilibili_view.json"),
+	})
+
+	_, err := biliBili{}.ParseShareUrl(context.Background(), "https://www.bilibili.com/video/BV1GJ411x7h7?p=9")
+	if !errors.Is(err, ErrInvalidLink) {
+		t.Errorf("err = %v, want ErrInvalidLink", err)
+	}
+}
+
+func TestBiliBiliPlayUrlPrefersAVC(t *testing.T) {
+	fakePlatforms(t, map[string]http.HandlerFunc{
+		"api.bilibili.com/x/player/playurl": bilibiliPlayUrl(t, "137649200", "bilibili_playurl.json"),
+	})
+
+	stream, err := biliBili{}.playUrl(context.Background(), "BV1GJ411x7h7", "137649200")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// HEVC的画质更高, 仍选择H.264中画质最高的流
+	if stream.videoUrl != "https://upos-sz-mirrorcos.bilivideo.com/137649200-80-avc.m4s" {
+		t.Errorf("video url = %q", stream.videoUrl)
+	}
+	if stream.audioUrl != "https://upos-sz-mirrorcos.bilivideo.com/137649200-30280.m4s" {
+		t.Errorf("audio url = %q", stream.audioUrl)
+	}
+}
+
+func TestBiliBiliParsePlayUrlFailed(t *testing.T) {
+	fakePlatforms(t, map[string]http.HandlerFunc{
+		"api.bilibili.com/x/web-interface/view": serveFixture(t, "bilibili_view.json"),
+		"api.bilibili.com/x/player/playurl":     bilibiliPlayUrl(t, "137649199", "bilibili_playurl_blocked.json"),
+	})
+
+	// 指定分P获取失败时返回错误, 不用其他分P代替
+	_, err := biliBili{}.ParseVideoID(context.Background(), "BV1GJ411x7h7")
+	if !errors.Is(err, ErrUpstreamBlocked) {
+		t.Errorf("err = %v, want ErrUpstreamBlocked", err)
+	}
+}
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/models"
+	"github.com/tidwall/gjson"
+)
+
+var (
+	// douYinRouterDataReg 分享页中保存作品数据的脚本
+	douYinRouterDataReg = regexp.MustCompile(`(?s)window\._ROUTER_DATA\s*=\s*(.*?)</script>`)
+	// douYinVideoIdReg 从作品页路径中提取作品id
+	douYinVideoIdReg = regexp.MustCompile(`/(?:share/)?(?:video|note|slides)/(\d+)`)
+)
+
+// douYinShareBaseUrl 作品分享页地址
+const douYinShareBaseUrl = "https://www.iesdouyin.com/share/video/"
+
+type douYin struct{}
+
+// ParseShareUrl 解析抖音分享链接, 短链会先跟随跳转获取作品id
+func (d douYin) ParseShareUrl(ctx context.Context, shareUrl string) (*models.VideoParseInfo, error) {
+	videoUrl := shareUrl
+	if u, err := url.Parse(shareUrl); err == nil && u.Hostname() == "v.douyin.com" {
+		location, err := redirectLocation(ctx, SourceDouYin, shareUrl)
+		if err != nil {
+			return nil, fmt.Errorf("douyin short url redirect: %w", err)
+		}
+		videoUrl = location
+	}
+
+	videoId, err := d.videoIdFromUrl(videoUrl)
+	if err != nil {
+		return nil, err
+	}
+	return d.ParseVideoID(ctx, videoId)
+}
+
+// ParseVideoID 根据作品id获取分享页并解析作品信息
+func (d douYin) ParseVideoID(ctx context.Context, videoId string) (*models.VideoParseInfo, error) {
+	client := newParseClient()
+	res, err := client.R().
+		SetContext(ctx).
+		SetHeader(HttpHeaderUserAgent, userAgent(SourceDouYin, DefaultUserAgent)).
+		Get(douYinShareBaseUrl + videoId)
+	if err != nil {
+		return nil, UpstreamRequestError("douyin request share page", err)
+	}
+	if res.StatusCode() != 200 {
+		return nil, UpstreamStatusError("douyin share page", res.StatusCode())
+	}
+
+	videoInfoRes, err := routerDataVideoInfo(res.Body())
+	if err != nil {
+		return nil, fmt.Errorf("douyin %w", err)
+	}
+
+	item := videoInfoRes.Get("item_list.0")
+	if !item.Exists() {
+		// 作品被删除或设为私密时 item_list 为空, filter_list 中记录原因
+		reason := videoInfoRes.Get("filter_list.0.filter_reason").String()
+		return nil, fmt.Errorf("%w: douyin video %s: %s", ErrContentUnavailable, videoId, reason)
+	}
+
+	parseInfo := &models.VideoParseInfo{
+		Title:    item.Get("desc").String(),
+		CoverUrl: item.Get("video.cover.url_list.0").String(),
+		MusicUrl: firstNonEmpty(item.Get("music.play_url.url_list.0").String(), item.Get("music.play_url.uri").String()),
+	}
+	parseInfo.Author.Uid = item.Get("author.sec_uid").String()
+	parseInfo.Author.Name = item.Get("author.nickname").String()
+	parseInfo.Author.Avatar = item.Get("author.avatar_thumb.url_list.0").String()
+
+	// 图集作品
+	for _, image := range item.Get("images").Array() {
+		if imageUrl := image.Get("url_list.0").String(); imageUrl != "" {
+			parseInfo.Images = append(parseInfo.Images, imageUrl)
+		}
+	}
+
+	// 视频作品, 将带水印的 playwm 地址替换为无水印的 play 地址
+	if len(parseInfo.Images) == 0 {
+		videoUrl := strings.ReplaceAll(item.Get("video.play_addr.url_list.0").String(), "playwm", "play")
+		if videoUrl == "" {
+			return nil, fmt.Errorf("%w: douyin parse video url", ErrParseFailed)
+		}
+		// 无水印地址会再跳转到CDN, 尽量返回最终地址, 失败时保留原地址
+		if location, err := redirectLocation(ctx, SourceDouYin, videoUrl); err == nil && location != "" {
+			videoUrl = location
+		}
+		parseInfo.VideoUrl = videoUrl
+	}
+
+	return parseInfo, nil
+}
+
+// routerDataVideoInfo 从分享页的 _ROUTER_DATA 中提取作品数据, 抖音和西瓜视频的分享页使用相同结构
+func routerDataVideoInfo(body []byte) (gjson.Result, error) {
+	findRes := douYinRouterDataReg.FindSubmatch(body)
+	if len(findRes) < 2 {
+		return gjson.Result{}, fmt.Errorf("%w: router data", ErrParseFailed)
+	}
+	routerData := gjson.ParseBytes(findRes[1])
+
+	// 作品数据位于 loaderData 下形如 video_(id)/page 或 note_(id)/page 的key中
+	var videoInfoRes gjson.Result
+	routerData.Get("loaderData").ForEach(func(key, value gjson.Result) bool {
+		if value.Get("videoInfoRes").Exists() {
+			videoInfoRes = value.Get("videoInfoRes")
+			return false
+		}
+		return true
+	})
+	if !videoInfoRes.Exists() {
+		return gjson.Result{}, fmt.Errorf("%w: video info", ErrParseFailed)
+	}
+	return videoInfoRes, nil
+}
+
+// videoIdFromUrl 从作品页链接中提取作品id
+func (d douYin) videoIdFromUrl(videoUrl string) (string, error) {
+	if findRes := douYinVideoIdReg.FindStringSubmatch(videoUrl); len(findRes) == 2 {
+		return findRes[1], nil
+	}
+	// 网页端精选页等链接通过 modal_id 指定作品
+	if u, err := url.Parse(videoUrl); err == nil {
+		if videoId := u.Query().Get("modal_id"); videoId != "" {
+			return videoId, nil
+		}
+	}
+	return "", fmt.Errorf("%w: douyin parse video id from url: %s", ErrInvalidLink, videoUrl)
+}
+
+// firstNonEmpty 返回第一个非空字符串
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestDouYinParseShareUrlFollowsShortLink(t *testing.T) {
+	fakePlatforms(t, map[string]http.HandlerFunc{
+		"v.douyin.com/iRNBho6u/":                            redirectTo("https://www.iesdouyin.com/share/video/7301234567890123456/?region=CN&mid=7301234"),
+		"www.iesdouyin.com/share/video/7301234567890123456": serveFixture(t, "douyin_video.html"),
+		"aweme.snssdk.com/aweme/v1/play/": func(w http.ResponseWriter, r *http.Request) {
+			// 应请求无水印的 play 地址
+			if r.URL.Query().Get("video_id") != "v0200fg10000ckq1abc" {
+				t.Errorf("video_id = %q", r.URL.Query().Get("video_id"))
+			}
+			redirectTo("https://v26-web.douyinvod.com/0a1b2c/video.mp4")(w, r)
+		},
+	})
+
+	parseInfo, err := douYin{}.ParseShareUrl(context.Background(), "https://v.douyin.com/iRNBho6u/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parseInfo.Title != "傍晚的海边 #日落" {
+		t.Errorf("title = %q", parseInfo.Title)
+	}
+	if parseInfo.VideoUrl != "https://v26-web.douyinvod.com/0a1b2c/video.mp4" {
+		t.Errorf("video url = %q", parseInfo.VideoUrl)
+	}
+	if parseInfo.CoverUrl != "https://p3-sign.douyinpic.com/tos-cn-p-0015/cover.jpeg" {
+		t.Errorf("cover url = %q", parseInfo.CoverUrl)
+	}
+	if parseInfo.MusicUrl != "https://sf3-cdn-tos.douyinstatic.com/obj/ies-music/7301234.mp3" {
+		t.Errorf("music url = %q", parseInfo.MusicUrl)
+	}
+	if parseInfo.Author.Uid != "MS4wLjABAAAAx1y2z3" || parseInfo.Author.Name != "海边的阿杰" {
+		t.Errorf("author = %+v", parseInfo.Author)
+	}
+	if len(parseInfo.Images) != 0 {
+		t.Errorf("images = %v, want none", parseInfo.Images)
+	}
+}
+
+func TestDouYinParseVideoIDImages(t *testing.T) {
+	fakePlatforms(t, map[string]http.HandlerFunc{
+		"www.iesdouyin.com/share/video/7309876543210987654": serveFixture(t, "douyin_images.html"),
+	})
+
+	parseInfo, err := douYin{}.ParseVideoID(context.Background(), "7309876543210987654")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parseInfo.Images) != 2 || parseInfo.Images[1] != "https://p3-sign.douyinpic.com/tos-cn-i-0813/2.webp" {
+		t.Errorf("images = %v", parseInfo.Images)
+	}
+	if parseInfo.VideoUrl != "" {
+		t.Errorf("video url = %q, want empty for image post", parseInfo.VideoUrl)
+	}
+	// url_list 为空时使用 uri
+	if parseInfo.MusicUrl != "https://sf3-cdn-tos.douyinstatic.com/obj/ies-music/7309876.mp3" {
+		t.Errorf("music url = %q", parseInfo.MusicUrl)
+	}
+}
+
+func TestDouYinParseVideoIDRemoved(t *testing.T) {
+	fakePlatforms(t, map[string]http.HandlerFunc{
+		"www.iesdouyin.com/share/video/7300000000000000000": serveFixture(t, "douyin_removed.html"),
+	})
+
+	_, err := douYin{}.ParseVideoID(context.Background(), "7300000000000000000")
+	if !errors.Is(err, ErrContentUnavailable) {
+		t.Fatalf("err = %v, want ErrContentUnavailable", err)
+	}
+}
+
+func TestDouYinParseShareUrlExpiredShortLink(t *testing.T) {
+	// 失效的短链直接返回首页而不是跳转
+	fakePlatforms(t, map[string]http.HandlerFunc{
+		"v.douyin.com/expired/": func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("<html></html>"))
+		},
+	})
+
+	_, err := douYin{}.ParseShareUrl(context.Background(), "https://v.douyin.com/expired/")
+	if !errors.Is(err, ErrInvalidLink) {
+		t.Fatalf("err = %v, want ErrInvalidLink", err)
+	}
+}
+
+func TestDouYinParseVideoIDWithoutRouterData(t *testing.T) {
+	fakePlatforms(t, map[string]http.HandlerFunc{
+		"www.iesdouyin.com/share/video/7301234567890123456": func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("<html><body>验证码</body></html>"))
+		},
+	})
+
+	_, err := douYin{}.ParseVideoID(context.Background(), "7301234567890123456")
+	if !errors.Is(err, ErrParseFailed) {
+		t.Fatalf("err = %v, want ErrParseFailed", err)
+	}
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/upstream"
+)
+
+// 解析和代理失败的错误分类, 具体错误通过 fmt.Errorf("%w: ...") 包装, 使用 errors.Is 判断
+var (
+	ErrUnsupportedPlatform = errors.New("unsupported platform")       // 链接所属平台不支持
+	ErrInvalidLink         = errors.New("invalid link")               // 链接格式错误或无法提取作品id
+	ErrContentUnavailable  = errors.New("content deleted or private") // 作品被删除或设为私密
+	ErrParseFailed         = errors.New("parse failed")               // 平台页面结构无法识别
+	ErrUpstreamBlocked     = errors.New("upstream blocked")           // 平台拒绝访问或返回异常
+	ErrUpstreamTimeout     = errors.New("upstream timeout")           // 请求平台超时
+	ErrUpstreamUnavailable = errors.New("upstream unavailable")       // 平台连续失败已熔断, 请求未发出
+	ErrRateLimited         = errors.New("upstream rate limited")      // 平台限流
+	ErrMediaTooLarge       = errors.New("media too large")            // 远程媒体超过大小限制
+	ErrMediaProcessing     = errors.New("media processing failed")    // 转码、去水印或图片处理失败
+	ErrPlatformDisabled    = errors.New("platform disabled")          // 平台的解析开关已关闭
+)
+
+// 文件处理失败的错误分类
+var (
+	ErrObjectNotFound   = errors.New("object not found")   // 对象存储中的文件不存在或已过期
+	ErrInvalidPageRange = errors.New("invalid page range") // 页码范围格式错误或超出文件页数
+)
+
+// IsTimeout 判断错误是否由超时引起
+func IsTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// UpstreamRequestError 归类请求平台或远程媒体时的网络错误
+func UpstreamRequestError(action string, err error) error {
+	var statusErr *upstream.StatusError
+	if errors.As(err, &statusErr) {
+		return UpstreamStatusError(action, statusErr.StatusCode)
+	}
+	if errors.Is(err, upstream.ErrCircuitOpen) {
+		return fmt.Errorf("%w: %s: %v", ErrUpstreamUnavailable, action, err)
+	}
+	if IsTimeout(err) {
+		return fmt.Errorf("%w: %s: %v", ErrUpstreamTimeout, action, err)
+	}
+	return fmt.Errorf("%w: %s: %v", ErrUpstreamBlocked, action, err)
+}
+
+// UpstreamStatusError 根据平台返回的HTTP状态码归类错误
+func UpstreamStatusError(action string, statusCode int) error {
+	var kind error
+	switch statusCode {
+	case http.StatusNotFound, http.StatusGone:
+		kind = ErrContentUnavailable
+	case http.StatusTooManyRequests:
+		kind = ErrRateLimited
+	case http.StatusGatewayTimeout, http.StatusRequestTimeout:
+		kind = ErrUpstreamTimeout
+	default:
+		kind = ErrUpstreamBlocked
+	}
+	return fmt.Errorf("%w: %s status: %d %s", kind, action, statusCode, http.StatusText(statusCode))
+}
+
+// errorKinds 错误分类对应的指标标签
+var errorKinds = []struct {
+	err  error
+	kind string
+}{
+	{ErrUnsupportedPlatform, "unsupported_platform"},
+	{ErrInvalidLink, "invalid_link"},
+	{ErrContentUnavailable, "content_unavailable"},
+	{ErrParseFailed, "parse_failed"},
+	{ErrUpstreamBlocked, "upstream_blocked"},
+	{ErrUpstreamTimeout, "upstream_timeout"},
+	{ErrUpstreamUnavailable, "upstream_unavailable"},
+	{ErrRateLimited, "rate_limited"},
+	{ErrMediaTooLarge, "media_too_large"},
+	{ErrMediaProcessing, "media_processing_failed"},
+	{ErrPlatformDisabled, "platform_disabled"},
+}
+
+// errorKind 返回错误分类的指标标签, 成功为 success, 未分类的错误为 other
+func errorKind(err error) string {
+	if err == nil {
+		return "success"
+	}
+	for _, k := range errorKinds {
+		if errors.Is(err, k.err) {
+			return k.kind
+		}
+	}
+	return "other"
+}
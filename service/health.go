@@ -0,0 +1,194 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/models"
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/redis/go-redis/v9"
+)
+
+// parseHealthKeyPrefix 平台探测结果在Redis中的key前缀
+const parseHealthKeyPrefix = "parse:health:"
+
+// ParseCanaryLinks 解析各平台探测链接配置
+//
+// 格式为 平台=分享链接, 多个平台以分号分隔, 例如:
+//
+//	douyin=https://v.douyin.com/xxxx/;bilibili=https://www.bilibili.com/video/BV1xx411c7mD
+func ParseCanaryLinks(spec string) (map[string]string, error) {
+	links := make(map[string]string)
+	for _, item := range strings.Split(spec, ";") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		source, link, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid canary link %q", item)
+		}
+		source, link = strings.TrimSpace(source), strings.TrimSpace(link)
+		if _, ok := videoSourceInfoMapping[source]; !ok {
+			return nil, fmt.Errorf("canary link source %s is not supported", source)
+		}
+		if u, err := url.Parse(link); err != nil || u.Host == "" {
+			return nil, fmt.Errorf("invalid canary link url %q for %s", link, source)
+		}
+		links[source] = link
+	}
+	return links, nil
+}
+
+// SupportedPlatforms 返回已注册的平台及其域名, 按平台名排序
+func SupportedPlatforms() []models.PlatformInfo {
+	platforms := make([]models.PlatformInfo, 0, len(videoSourceInfoMapping))
+	for source, info := range videoSourceInfoMapping {
+		platforms = append(platforms, models.PlatformInfo{
+			Source:   source,
+			Domains:  info.VideoShareUrlDomain,
+			IdParser: info.VideoIdParser != nil,
+		})
+	}
+	sort.Slice(platforms, func(i, j int) bool { return platforms[i].Source < platforms[j].Source })
+	return platforms
+}
+
+// CircuitBreakers 返回已请求过的域名的熔断器状态
+func CircuitBreakers() []models.CircuitBreaker {
+	states := httpClient.BreakerStates()
+	breakers := make([]models.CircuitBreaker, 0, len(states))
+	for _, state := range states {
+		breakers = append(breakers, models.CircuitBreaker{
+			Host:                state.Host,
+			State:               state.State,
+			ConsecutiveFailures: state.ConsecutiveFailures,
+			OpenedAt:            state.OpenedAt,
+			Trips:               state.Trips,
+		})
+	}
+	return breakers
+}
+
+// ParseProber 定期使用探测链接检查各平台解析是否正常, 结果保存在Redis中
+type ParseProber struct {
+	redis    redis.UniversalClient
+	links    map[string]string // 平台 => 探测链接
+	interval time.Duration
+}
+
+func NewParseProber(redis redis.UniversalClient, links map[string]string, interval time.Duration) *ParseProber {
+	return &ParseProber{
+		redis:    redis,
+		links:    links,
+		interval: interval,
+	}
+}
+
+// Start 在后台启动定期探测, ctx 取消后停止; 未配置探测链接或间隔时不启动
+func (p *ParseProber) Start(ctx context.Context) {
+	if len(p.links) == 0 || p.interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+		for {
+			p.ProbeAll(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// ProbeAll 并发探测所有配置了探测链接的平台
+func (p *ParseProber) ProbeAll(ctx context.Context) {
+	var wg sync.WaitGroup
+	for source, link := range p.links {
+		wg.Add(1)
+		go func(source, link string) {
+			defer wg.Done()
+			p.probe(ctx, source, link)
+		}(source, link)
+	}
+	wg.Wait()
+}
+
+func (p *ParseProber) probe(ctx context.Context, source, link string) {
+	health, err := p.get(ctx, source)
+	if err != nil {
+		log.Errorw("parse prober load health failed", "platform", source, "error", err)
+		health = &models.ParseHealth{Source: source}
+	}
+
+	start := time.Now()
+	parseInfo, err := ParseVideoShareUrl(ctx, link)
+	if err == nil && parseInfo.VideoUrl == "" && len(parseInfo.Images) == 0 {
+		err = errors.New("parse result has no video or images")
+	}
+	now := time.Now()
+
+	health.CanaryUrl = link
+	health.LatencyMs = now.Sub(start).Milliseconds()
+	health.LastCheckedAt = &now
+	if err != nil {
+		log.Warnw("parse prober failed", "platform", source, "error", err)
+		health.Status = models.ParseHealthDown
+		health.Error = err.Error()
+		health.ConsecutiveFailures++
+	} else {
+		health.Status = models.ParseHealthUp
+		health.Error = ""
+		health.ConsecutiveFailures = 0
+		health.LastSuccessAt = &now
+	}
+
+	data, err := json.Marshal(health)
+	if err != nil {
+		log.Errorw("parse prober encode health failed", "platform", source, "error", err)
+		return
+	}
+	if err := p.redis.Set(ctx, parseHealthKeyPrefix+source, data, 0).Err(); err != nil {
+		log.Errorw("parse prober save health failed", "platform", source, "error", err)
+	}
+}
+
+// Status 返回所有已注册平台的最近探测结果, 未探测的平台状态为 unknown
+func (p *ParseProber) Status(ctx context.Context) ([]models.ParseHealth, error) {
+	platforms := SupportedPlatforms()
+	status := make([]models.ParseHealth, 0, len(platforms))
+	for _, platform := range platforms {
+		health, err := p.get(ctx, platform.Source)
+		if err != nil {
+			return nil, err
+		}
+		health.CanaryUrl = p.links[platform.Source]
+		status = append(status, *health)
+	}
+	return status, nil
+}
+
+func (p *ParseProber) get(ctx context.Context, source string) (*models.ParseHealth, error) {
+	data, err := p.redis.Get(ctx, parseHealthKeyPrefix+source).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return &models.ParseHealth{Source: source, Status: models.ParseHealthUnknown}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get parse health failed: %v", err)
+	}
+	health := &models.ParseHealth{}
+	if err := json.Unmarshal(data, health); err != nil {
+		return nil, fmt.Errorf("decode parse health failed: %v", err)
+	}
+	return health, nil
+}
@@ -0,0 +1,217 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/metrics"
+	"github.com/disintegration/imaging"
+	_ "golang.org/x/image/webp" // 注册WebP解码器
+)
+
+const (
+	// maxImageDimension 输出图片宽高上限
+	maxImageDimension = 4096
+	// maxImagePixels 输入图片像素上限, 防止超大图片耗尽内存
+	maxImagePixels = 100_000_000
+	// imageEncodeTimeout 调用ffmpeg编码WebP的最长时间
+	imageEncodeTimeout = 30 * time.Second
+)
+
+// 图片缩放模式
+const (
+	ImageFitContain = "contain" // 等比缩放至框内, 默认
+	ImageFitCover   = "cover"   // 等比缩放并居中裁剪填满
+	ImageFitFill    = "fill"    // 拉伸至指定宽高
+)
+
+// ImageOptions 图片处理参数, 同时支持query和JSON
+type ImageOptions struct {
+	Width   int    `json:"w" query:"w"`           // 目标宽度, 0表示按比例
+	Height  int    `json:"h" query:"h"`           // 目标高度, 0表示按比例
+	Fit     string `json:"fit" query:"fit"`       // 缩放模式: contain/cover/fill
+	Crop    string `json:"crop" query:"crop"`     // 缩放前裁剪区域, 格式为 x,y,w,h
+	Format  string `json:"format" query:"format"` // 输出格式: jpeg/png/webp, 为空时保持原格式
+	Quality int    `json:"q" query:"q"`           // 压缩质量(1-100), 默认80
+}
+
+// IsZero 判断是否未指定任何处理参数
+func (o *ImageOptions) IsZero() bool {
+	return *o == ImageOptions{}
+}
+
+// Normalize 校验参数并填充默认值
+func (o *ImageOptions) Normalize() error {
+	if o.Width < 0 || o.Height < 0 || o.Width > maxImageDimension || o.Height > maxImageDimension {
+		return fmt.Errorf("宽高需在0-%d之间", maxImageDimension)
+	}
+	switch o.Fit {
+	case "":
+		o.Fit = ImageFitContain
+	case ImageFitContain, ImageFitCover, ImageFitFill:
+	default:
+		return fmt.Errorf("不支持的缩放模式: %s", o.Fit)
+	}
+	switch strings.ToLower(o.Format) {
+	case "":
+	case "jpg", "jpeg":
+		o.Format = "jpeg"
+	case "png", "webp":
+		o.Format = strings.ToLower(o.Format)
+	default:
+		return fmt.Errorf("不支持的图片格式: %s", o.Format)
+	}
+	if o.Quality == 0 {
+		o.Quality = 80
+	}
+	if o.Quality < 1 || o.Quality > 100 {
+		return fmt.Errorf("压缩质量需在1-100之间")
+	}
+	if o.Crop != "" {
+		if _, err := parseCropRect(o.Crop); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CacheKey 返回规范化后的参数串, 用于构造缓存key
+func (o *ImageOptions) CacheKey() string {
+	return fmt.Sprintf("w=%d&h=%d&fit=%s&crop=%s&format=%s&q=%d", o.Width, o.Height, o.Fit, o.Crop, o.Format, o.Quality)
+}
+
+// ProcessImage 按参数处理图片: 按EXIF方向旋转、裁剪、缩放、格式转换和压缩, 返回图片数据和Content-Type
+func ProcessImage(ctx context.Context, ffmpeg string, data []byte, opts ImageOptions) ([]byte, string, error) {
+	cfg, srcFormat, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("无法识别的图片: %v", err)
+	}
+	if cfg.Width*cfg.Height > maxImagePixels {
+		return nil, "", fmt.Errorf("图片尺寸过大: %dx%d", cfg.Width, cfg.Height)
+	}
+
+	// AutoOrientation 会根据EXIF方向信息旋转图片, 输出图片不再携带EXIF
+	img, err := imaging.Decode(bytes.NewReader(data), imaging.AutoOrientation(true))
+	if err != nil {
+		return nil, "", fmt.Errorf("解码图片失败: %v", err)
+	}
+
+	if opts.Crop != "" {
+		rect, err := parseCropRect(opts.Crop)
+		if err != nil {
+			return nil, "", err
+		}
+		rect = rect.Intersect(img.Bounds())
+		if rect.Empty() {
+			return nil, "", fmt.Errorf("裁剪区域超出图片范围")
+		}
+		img = imaging.Crop(img, rect)
+	}
+
+	img = resizeImage(img, opts)
+
+	format := opts.Format
+	if format == "" {
+		format = srcFormat
+	}
+	buf := &bytes.Buffer{}
+	switch format {
+	case "png":
+		if err := imaging.Encode(buf, img, imaging.PNG); err != nil {
+			return nil, "", fmt.Errorf("编码PNG失败: %v", err)
+		}
+		return buf.Bytes(), "image/png", nil
+	case "webp":
+		// 标准库不支持WebP编码, 先无损编码为PNG再交给ffmpeg转换
+		if err := imaging.Encode(buf, img, imaging.PNG, imaging.PNGCompressionLevel(-1)); err != nil {
+			return nil, "", fmt.Errorf("编码PNG失败: %v", err)
+		}
+		webp, err := encodeWebP(ctx, ffmpeg, buf.Bytes(), opts.Quality)
+		if err != nil {
+			return nil, "", err
+		}
+		return webp, "image/webp", nil
+	default:
+		// 其他格式(jpeg/gif/webp源图等)默认输出JPEG
+		if err := imaging.Encode(buf, img, imaging.JPEG, imaging.JPEGQuality(opts.Quality)); err != nil {
+			return nil, "", fmt.Errorf("编码JPEG失败: %v", err)
+		}
+		return buf.Bytes(), "image/jpeg", nil
+	}
+}
+
+// resizeImage 按缩放模式调整图片尺寸, 不放大图片
+func resizeImage(img image.Image, opts ImageOptions) image.Image {
+	w, h := opts.Width, opts.Height
+	if w == 0 && h == 0 {
+		return img
+	}
+	bounds := img.Bounds()
+	if w >= bounds.Dx() && h >= bounds.Dy() {
+		return img
+	}
+	// 只指定一边时按比例缩放
+	if w == 0 || h == 0 {
+		if (w > 0 && w >= bounds.Dx()) || (h > 0 && h >= bounds.Dy()) {
+			return img
+		}
+		return imaging.Resize(img, w, h, imaging.Lanczos)
+	}
+	switch opts.Fit {
+	case ImageFitCover:
+		return imaging.Fill(img, w, h, imaging.Center, imaging.Lanczos)
+	case ImageFitFill:
+		return imaging.Resize(img, w, h, imaging.Lanczos)
+	default:
+		return imaging.Fit(img, w, h, imaging.Lanczos)
+	}
+}
+
+// parseCropRect 解析 x,y,w,h 格式的裁剪区域
+func parseCropRect(crop string) (image.Rectangle, error) {
+	parts := strings.Split(crop, ",")
+	if len(parts) != 4 {
+		return image.Rectangle{}, fmt.Errorf("无效的裁剪区域: %s", crop)
+	}
+	values := make([]int, 4)
+	for i, part := range parts {
+		v, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || v < 0 {
+			return image.Rectangle{}, fmt.Errorf("无效的裁剪区域: %s", crop)
+		}
+		values[i] = v
+	}
+	if values[2] == 0 || values[3] == 0 {
+		return image.Rectangle{}, fmt.Errorf("裁剪区域宽高不能为0")
+	}
+	return image.Rect(values[0], values[1], values[0]+values[2], values[1]+values[3]), nil
+}
+
+// encodeWebP 使用ffmpeg将PNG数据编码为WebP, 请求取消时终止编码
+func encodeWebP(ctx context.Context, ffmpeg string, pngData []byte, quality int) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, imageEncodeTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, ffmpeg,
+		"-hide_banner", "-loglevel", "error",
+		"-f", "png_pipe", "-i", "pipe:0",
+		"-c:v", "libwebp",
+		"-quality", strconv.Itoa(quality),
+		"-f", "webp", "pipe:1")
+	cmd.Stdin = bytes.NewReader(pngData)
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+	start := time.Now()
+	err := cmd.Run()
+	metrics.ObserveFFmpeg("webp", start, err)
+	if err != nil {
+		return nil, fmt.Errorf("FFmpeg编码WebP失败: %v, 输出: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
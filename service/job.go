@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/models"
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// jobKeyPrefix 任务在Redis中的key前缀
+const jobKeyPrefix = "job:"
+
+// ErrJobNotFound 任务不存在或已过期
+var ErrJobNotFound = errors.New("job not found")
+
+// ErrJobQueueFull 等待执行的任务已达上限
+var ErrJobQueueFull = errors.New("job queue full")
+
+// JobRunner 任务执行方法, 返回结果文件key
+type JobRunner func(ctx context.Context, job *models.Job) (string, error)
+
+// JobManager 异步任务管理, 任务状态保存在Redis中, 在本进程内执行
+type JobManager struct {
+	redis     redis.UniversalClient
+	runners   map[string]JobRunner
+	slots     chan struct{} // 限制同时执行的任务数
+	pending   chan struct{} // 已提交未完成的任务, 包括执行中的任务, 满时拒绝新任务
+	timeout   time.Duration // 单个任务最长执行时间
+	retention time.Duration // 任务记录保留时间
+}
+
+// NewJobManager 创建任务管理, 最多同时执行 workers 个任务, 另有 queueSize 个任务排队等待
+func NewJobManager(redis redis.UniversalClient, workers, queueSize int, timeout, retention time.Duration) *JobManager {
+	if workers <= 0 {
+		workers = 1
+	}
+	if queueSize < 0 {
+		queueSize = 0
+	}
+	return &JobManager{
+		redis:     redis,
+		runners:   make(map[string]JobRunner),
+		slots:     make(chan struct{}, workers),
+		pending:   make(chan struct{}, workers+queueSize),
+		timeout:   timeout,
+		retention: retention,
+	}
+}
+
+// Register 注册任务类型的执行方法, 需在提交任务前完成
+func (m *JobManager) Register(jobType string, runner JobRunner) {
+	m.runners[jobType] = runner
+}
+
+// Supports 判断任务类型是否已注册
+func (m *JobManager) Supports(jobType string) bool {
+	_, ok := m.runners[jobType]
+	return ok
+}
+
+// Submit 创建任务并在后台执行, 排队的任务已满时返回 ErrJobQueueFull
+func (m *JobManager) Submit(ctx context.Context, jobType string, params map[string]string) (*models.Job, error) {
+	runner, ok := m.runners[jobType]
+	if !ok {
+		return nil, fmt.Errorf("unsupported job type: %s", jobType)
+	}
+	select {
+	case m.pending <- struct{}{}:
+	default:
+		return nil, ErrJobQueueFull
+	}
+	now := time.Now()
+	job := &models.Job{
+		ID:        uuid.NewString(),
+		Type:      jobType,
+		Status:    models.JobStatusPending,
+		Params:    params,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := m.save(ctx, job); err != nil {
+		<-m.pending
+		return nil, err
+	}
+	// 后台任务使用副本, 避免与调用方并发读写
+	running := *job
+	go m.run(&running, runner)
+	return job, nil
+}
+
+// Get 查询任务
+func (m *JobManager) Get(ctx context.Context, id string) (*models.Job, error) {
+	data, err := m.redis.Get(ctx, jobKeyPrefix+id).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrJobNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get job failed: %v", err)
+	}
+	job := &models.Job{}
+	if err := json.Unmarshal(data, job); err != nil {
+		return nil, fmt.Errorf("decode job failed: %v", err)
+	}
+	return job, nil
+}
+
+func (m *JobManager) run(job *models.Job, runner JobRunner) {
+	defer func() { <-m.pending }()
+	m.slots <- struct{}{}
+	defer func() { <-m.slots }()
+	// 执行方法panic时标记任务失败, 避免进程退出且任务一直处于执行中
+	defer func() {
+		if r := recover(); r != nil {
+			log.Errorf("job %s (%s) panic: %v\n%s", job.ID, job.Type, r, debug.Stack())
+			m.update(job, models.JobStatusFailed, "", fmt.Sprintf("panic: %v", r))
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+
+	m.update(job, models.JobStatusRunning, "", "")
+	resultKey, err := runner(ctx, job)
+	if err != nil {
+		log.Errorf("job %s (%s) failed: %v", job.ID, job.Type, err)
+		m.update(job, models.JobStatusFailed, "", err.Error())
+		return
+	}
+	m.update(job, models.JobStatusSucceeded, resultKey, "")
+}
+
+func (m *JobManager) update(job *models.Job, status models.JobStatus, resultKey, errMsg string) {
+	job.Status = status
+	job.ResultKey = resultKey
+	job.Error = errMsg
+	job.UpdatedAt = time.Now()
+	// 使用独立的context, 保证超时任务的失败状态也能写回
+	if err := m.save(context.Background(), job); err != nil {
+		log.Errorf("job %s save status failed: %v", job.ID, err)
+	}
+}
+
+func (m *JobManager) save(ctx context.Context, job *models.Job) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	if err := m.redis.Set(ctx, jobKeyPrefix+job.ID, data, m.retention).Err(); err != nil {
+		return fmt.Errorf("save job failed: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/models"
+	"github.com/redis/go-redis/v9"
+)
+
+// newTestJobManager 创建连接不可用Redis的任务管理, 保存任务状态失败只记录日志
+func newTestJobManager(t *testing.T, workers, queueSize int) *JobManager {
+	t.Helper()
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", DialTimeout: 100 * time.Millisecond, MaxRetries: -1})
+	t.Cleanup(func() { client.Close() })
+	return NewJobManager(client, workers, queueSize, time.Minute, time.Hour)
+}
+
+func TestJobPanicMarksJobFailed(t *testing.T) {
+	m := newTestJobManager(t, 1, 0)
+	m.pending <- struct{}{}
+	job := &models.Job{ID: "1", Type: "panicky"}
+	m.run(job, func(ctx context.Context, job *models.Job) (string, error) {
+		var params map[string]string
+		params["key"] = "value"
+		return "", nil
+	})
+	if job.Status != models.JobStatusFailed || job.Error == "" {
+		t.Errorf("job = %+v, want failed with error", job)
+	}
+	if len(m.pending) != 0 || len(m.slots) != 0 {
+		t.Errorf("pending = %d, slots = %d, want both released", len(m.pending), len(m.slots))
+	}
+}
+
+func TestJobSubmitQueueFull(t *testing.T) {
+	m := newTestJobManager(t, 1, 1)
+	m.Register("noop", func(ctx context.Context, job *models.Job) (string, error) { return "", nil })
+	// 一个执行中, 一个排队
+	m.pending <- struct{}{}
+	m.pending <- struct{}{}
+
+	if _, err := m.Submit(context.Background(), "noop", nil); !errors.Is(err, ErrJobQueueFull) {
+		t.Fatalf("err = %v, want ErrJobQueueFull", err)
+	}
+	if len(m.pending) != 2 {
+		t.Errorf("pending = %d, want 2", len(m.pending))
+	}
+}
+
+func TestJobSubmitReleasesSlotWhenSaveFails(t *testing.T) {
+	m := newTestJobManager(t, 1, 0)
+	m.Register("noop", func(ctx context.Context, job *models.Job) (string, error) { return "", nil })
+
+	if _, err := m.Submit(context.Background(), "noop", nil); err == nil {
+		t.Fatal("expected save error")
+	}
+	if len(m.pending) != 0 {
+		t.Errorf("pending = %d, want 0 after failed submit", len(m.pending))
+	}
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/models"
+	"github.com/tidwall/gjson"
+)
+
+// kuaiShouInitStateReg 分享页中保存页面数据的脚本
+var kuaiShouInitStateReg = regexp.MustCompile(`(?s)window\.INIT_STATE\s*=\s*(.*?)</script>`)
+
+type kuaiShou struct{}
+
+// ParseShareUrl 解析快手分享链接
+//
+// 快手分享页依赖短链跳转后携带的签名参数, 无法仅凭作品id获取, 因此没有id解析方法
+func (k kuaiShou) ParseShareUrl(ctx context.Context, shareUrl string) (*models.VideoParseInfo, error) {
+	pageUrl := shareUrl
+	if u, err := url.Parse(shareUrl); err == nil && u.Hostname() == "v.kuaishou.com" {
+		location, err := redirectLocation(ctx, SourceKuaiShou, shareUrl)
+		if err != nil {
+			return nil, fmt.Errorf("kuaishou short url redirect: %w", err)
+		}
+		pageUrl = location
+	}
+	// 长视频页面不包含作品数据, 统一使用普通作品页
+	pageUrl = strings.Replace(pageUrl, "/fw/long-video/", "/fw/photo/", 1)
+
+	client := newParseClient()
+	res, err := client.R().
+		SetContext(ctx).
+		SetHeader(HttpHeaderUserAgent, userAgent(SourceKuaiShou, DefaultUserAgent)).
+		SetHeader(HttpHeaderReferer, "https://v.kuaishou.com/").
+		Get(pageUrl)
+	if err != nil {
+		return nil, UpstreamRequestError("kuaishou request share page", err)
+	}
+	if res.StatusCode() != 200 {
+		return nil, UpstreamStatusError("kuaishou share page", res.StatusCode())
+	}
+
+	findRes := kuaiShouInitStateReg.FindSubmatch(res.Body())
+	if len(findRes) < 2 {
+		return nil, fmt.Errorf("%w: kuaishou parse init state", ErrParseFailed)
+	}
+
+	// 作品数据位于 INIT_STATE 中某个带有 photo 字段的key下, key名随版本变化
+	var photo gjson.Result
+	gjson.ParseBytes(findRes[1]).ForEach(func(key, value gjson.Result) bool {
+		if value.Get("photo").Exists() {
+			photo = value.Get("photo")
+			return false
+		}
+		return true
+	})
+	if !photo.Exists() {
+		return nil, fmt.Errorf("%w: kuaishou video", ErrContentUnavailable)
+	}
+
+	parseInfo := &models.VideoParseInfo{
+		Title:    photo.Get("caption").String(),
+		CoverUrl: photo.Get("coverUrls.0.url").String(),
+		MusicUrl: photo.Get("music.audioUrls.0.url").String(),
+	}
+	parseInfo.Author.Uid = firstNonEmpty(photo.Get("userEid").String(), photo.Get("userId").String())
+	parseInfo.Author.Name = photo.Get("userName").String()
+	parseInfo.Author.Avatar = photo.Get("headUrl").String()
+
+	// 图集作品, 图片地址由CDN域名和路径拼接
+	atlas := photo.Get("ext_params.atlas")
+	if cdn := atlas.Get("cdn.0").String(); cdn != "" {
+		for _, path := range atlas.Get("list").Array() {
+			parseInfo.Images = append(parseInfo.Images, "https://"+cdn+path.String())
+		}
+	}
+	if len(parseInfo.Images) == 0 {
+		parseInfo.VideoUrl = photo.Get("mainMvUrls.0.url").String()
+		if parseInfo.VideoUrl == "" {
+			return nil, fmt.Errorf("%w: kuaishou parse video url", ErrParseFailed)
+		}
+	}
+	return parseInfo, nil
+}
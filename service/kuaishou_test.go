@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestKuaiShouParseShareUrlVideo(t *testing.T) {
+	fakePlatforms(t, map[string]http.HandlerFunc{
+		"v.kuaishou.com/Kx9aB2": redirectTo("https://v.m.chenzhongtech.com/fw/long-video/3xq8abc5d6e7f8g?fid=0&cc=share_copylink&shareId=1772345"),
+		// 长视频页面统一替换为普通作品页
+		"v.m.chenzhongtech.com/fw/photo/3xq8abc5d6e7f8g": func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("shareId") != "1772345" {
+				t.Errorf("shareId = %q, want the signed params from the short link", r.URL.Query().Get("shareId"))
+			}
+			serveFixture(t, "kuaishou_video.html")(w, r)
+		},
+	})
+
+	parseInfo, err := kuaiShou{}.ParseShareUrl(context.Background(), "https://v.kuaishou.com/Kx9aB2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parseInfo.Title != "今天的晚霞太美了" {
+		t.Errorf("title = %q", parseInfo.Title)
+	}
+	if parseInfo.VideoUrl != "https://v2.kwaicdn.com/upic/2023/11/15/3xq8abc5d6e7f8g_b.mp4" {
+		t.Errorf("video url = %q", parseInfo.VideoUrl)
+	}
+	if parseInfo.MusicUrl != "https://p2.a.yximgs.com/bs2/music/3xq8abc.m4a" {
+		t.Errorf("music url = %q", parseInfo.MusicUrl)
+	}
+	if parseInfo.Author.Uid != "3xk2m3n4p5q6r7s" || parseInfo.Author.Name != "旅行的老王" {
+		t.Errorf("author = %+v", parseInfo.Author)
+	}
+}
+
+func TestKuaiShouParseShareUrlAtlas(t *testing.T) {
+	fakePlatforms(t, map[string]http.HandlerFunc{
+		"www.kuaishou.com/fw/photo/3xatlas1a2b3c4": serveFixture(t, "kuaishou_atlas.html"),
+	})
+
+	parseInfo, err := kuaiShou{}.ParseShareUrl(context.Background(), "https://www.kuaishou.com/fw/photo/3xatlas1a2b3c4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantImages := []string{
+		"https://p2.a.yximgs.com/ufile/atlas/3xatlas1a2b3c4_0.webp",
+		"https://p2.a.yximgs.com/ufile/atlas/3xatlas1a2b3c4_1.webp",
+	}
+	if len(parseInfo.Images) != len(wantImages) || parseInfo.Images[0] != wantImages[0] || parseInfo.Images[1] != wantImages[1] {
+		t.Errorf("images = %v, want %v", parseInfo.Images, wantImages)
+	}
+	if parseInfo.VideoUrl != "" {
+		t.Errorf("video url = %q, want empty for atlas", parseInfo.VideoUrl)
+	}
+	// 没有 userEid 时使用 userId
+	if parseInfo.Author.Uid != "987654321" {
+		t.Errorf("author uid = %q", parseInfo.Author.Uid)
+	}
+}
+
+func TestKuaiShouParseShareUrlRemoved(t *testing.T) {
+	fakePlatforms(t, map[string]http.HandlerFunc{
+		"www.kuaishou.com/fw/photo/3xremoved0000": serveFixture(t, "kuaishou_removed.html"),
+	})
+
+	_, err := kuaiShou{}.ParseShareUrl(context.Background(), "https://www.kuaishou.com/fw/photo/3xremoved0000")
+	if !errors.Is(err, ErrContentUnavailable) {
+		t.Fatalf("err = %v, want ErrContentUnavailable", err)
+	}
+}
+
+func TestKuaiShouShortLinkHostMustMatch(t *testing.T) {
+	// 路径中包含短链域名的普通链接不应按短链处理
+	fakePlatforms(t, map[string]http.HandlerFunc{
+		"www.kuaishou.com/fw/photo/3xq8abc5d6e7f8g": serveFixture(t, "kuaishou_video.html"),
+	})
+
+	parseInfo, err := kuaiShou{}.ParseShareUrl(context.Background(), "https://www.kuaishou.com/fw/photo/3xq8abc5d6e7f8g?from=v.kuaishou.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parseInfo.VideoUrl == "" {
+		t.Error("video url is empty")
+	}
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/upstream"
+)
+
+// mediaPlatformDomains 各平台媒体资源(CDN)所使用的域名
+var mediaPlatformDomains = map[string][]string{
+	"douyin":      {"douyinvod.com", "douyincdn.com", "douyinpic.com", "douyin.com", "amemv.com", "snssdk.com"},
+	"xiaohongshu": {"xhscdn.com", "xiaohongshu.com"},
+	"kuaishou":    {"kwaicdn.com", "kwimgs.com", "yximgs.com", "kuaishou.com"},
+	"weibo":       {"sinaimg.cn", "weibocdn.com", "weibo.com"},
+	"xigua":       {"ixigua.com", "bytecdn.cn", "pstatp.com"},
+	"bilibili":    {"bilivideo.com", "bilivideo.cn", "hdslb.com", "bilibili.com"},
+}
+
+// mediaPolicies 各平台媒体资源的默认请求策略, 缺少Referer时部分平台CDN会拒绝请求
+var mediaPolicies = map[string]upstream.HostPolicy{
+	"bilibili":    {Referer: "https://www.bilibili.com/"},
+	"xiaohongshu": {Referer: "https://www.xiaohongshu.com/", UserAgents: []string{DefaultUserAgent}},
+}
+
+// MediaPlatform 根据媒体地址的域名判断所属平台, 无法识别时返回空字符串
+func MediaPlatform(mediaURL string) string {
+	u, err := url.Parse(mediaURL)
+	if err != nil {
+		return ""
+	}
+	host := strings.ToLower(u.Hostname())
+	for platform, domains := range mediaPlatformDomains {
+		for _, domain := range domains {
+			if host == domain || strings.HasSuffix(host, "."+domain) {
+				return platform
+			}
+		}
+	}
+	return ""
+}
+
+// HostPolicies 返回各平台域名的默认请求策略, overrides 中的同名域名配置覆盖默认值
+//
+// overrides 的key也可以是平台名(如 douyin), 此时策略应用到该平台的分享链接域名和媒体域名,
+// 便于为整个平台指定代理池
+func HostPolicies(overrides map[string]upstream.HostPolicy) map[string]upstream.HostPolicy {
+	policies := make(map[string]upstream.HostPolicy)
+	for platform, policy := range mediaPolicies {
+		for _, domain := range platformDomains(platform) {
+			policies[domain] = policy
+		}
+	}
+	// 先应用平台策略, 再应用域名策略, 使具体域名的配置优先
+	for name, policy := range overrides {
+		for _, domain := range platformDomains(name) {
+			policies[domain] = policy.Merge(policies[domain])
+		}
+	}
+	for name, policy := range overrides {
+		if len(platformDomains(name)) == 0 {
+			policies[name] = policy.Merge(policies[name])
+		}
+	}
+	return policies
+}
+
+// PlatformDomains 返回所有已支持平台的分享链接域名和媒体域名, 作为媒体代理默认允许访问的域名
+func PlatformDomains() []string {
+	var domains []string
+	for platform := range mediaPlatformDomains {
+		domains = append(domains, platformDomains(platform)...)
+	}
+	for platform, info := range videoSourceInfoMapping {
+		if _, ok := mediaPlatformDomains[platform]; !ok {
+			domains = append(domains, info.VideoShareUrlDomain...)
+		}
+	}
+	return domains
+}
+
+// platformDomains 返回平台的分享链接域名和媒体域名, 不是平台名时返回nil
+func platformDomains(platform string) []string {
+	domains := append([]string{}, mediaPlatformDomains[platform]...)
+	if info, ok := videoSourceInfoMapping[platform]; ok {
+		domains = append(domains, info.VideoShareUrlDomain...)
+	}
+	return domains
+}
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// OfficeExtensions 支持转换为PDF的Office文档扩展名
+var OfficeExtensions = map[string]bool{
+	".docx": true,
+	".xlsx": true,
+	".pptx": true,
+}
+
+// ConvertOfficeToPDF 使用LibreOffice将Office文档转换为PDF, 返回生成的PDF路径
+//
+// 每次转换使用独立的工作目录和用户配置目录, 避免并发转换时共享LibreOffice配置导致的锁冲突
+func ConvertOfficeToPDF(ctx context.Context, soffice, inFile string) (string, error) {
+	if !OfficeExtensions[strings.ToLower(filepath.Ext(inFile))] {
+		return "", fmt.Errorf("不支持的文档格式: %s", filepath.Ext(inFile))
+	}
+
+	workDir := filepath.Dir(inFile)
+	outDir := filepath.Join(workDir, "out")
+	profileDir := filepath.Join(workDir, "profile")
+	for _, dir := range []string{outDir, profileDir} {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return "", fmt.Errorf("创建工作目录失败: %v", err)
+		}
+	}
+
+	cmd := exec.CommandContext(ctx, soffice,
+		"-env:UserInstallation=file://"+filepath.ToSlash(profileDir),
+		"--headless",
+		"--norestore",
+		"--nolockcheck",
+		"--convert-to", "pdf",
+		"--outdir", outDir,
+		inFile)
+	cmd.Dir = workDir
+	cmd.Env = append(os.Environ(), "HOME="+workDir)
+	// 超时被终止后, 最多再等待子进程输出5秒
+	cmd.WaitDelay = 5 * time.Second
+
+	if output, err := cmd.CombinedOutput(); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return "", fmt.Errorf("文档转换超时")
+		}
+		return "", fmt.Errorf("LibreOffice转换失败: %v, 输出: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	outFile := filepath.Join(outDir, strings.TrimSuffix(filepath.Base(inFile), filepath.Ext(inFile))+".pdf")
+	if _, err := os.Stat(outFile); err != nil {
+		return "", fmt.Errorf("未生成PDF文件: %v", err)
+	}
+	return outFile, nil
+}
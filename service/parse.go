@@ -0,0 +1,260 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/metrics"
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/models"
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/settings"
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/tracing"
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/upstream"
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/utils"
+	"github.com/go-resty/resty/v2"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// 视频渠道来源
+const (
+	SourceDouYin      = "douyin"      // 抖音
+	SourceXiaoHongShu = "xiaohongshu" // 小红书
+	SourceBiliBili    = "bilibili"    // 哔哩哔哩
+	SourceKuaiShou    = "kuaishou"    // 快手
+	SourceWeiBo       = "weibo"       // 微博
+	SourceXiGua       = "xigua"       // 西瓜视频
+)
+
+// 请求头
+const (
+	HttpHeaderUserAgent   = "User-Agent"
+	HttpHeaderReferer     = "Referer"
+	HttpHeaderContentType = "Content-Type"
+	HttpHeaderCookie      = "Cookie"
+
+	// DefaultUserAgent 移动端UA, 大部分平台的分享页只对移动端返回完整数据
+	DefaultUserAgent = "Mozilla/5.0 (iPhone; CPU iPhone OS 16_6 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.6 Mobile/15E148 Safari/604.1"
+	// DesktopUserAgent 桌面端UA
+	DesktopUserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"
+)
+
+// parseRequestTimeout 解析时单次请求平台接口的超时时间
+const parseRequestTimeout = 10 * time.Second
+
+// httpClient 解析和媒体请求共用的出站客户端, 启动时通过 SetHTTPClient 替换为按配置创建的客户端
+var httpClient = upstream.New(upstream.Config{Policies: HostPolicies(nil)})
+
+// SetHTTPClient 设置解析平台接口使用的出站客户端, 需在处理请求前调用
+func SetHTTPClient(client *upstream.Client) {
+	httpClient = client
+}
+
+// runtimeSettings 运行时配置, 启动时通过 SetSettings 设置, 未设置时使用默认配置
+var runtimeSettings *settings.Manager
+
+// SetSettings 设置解析使用的运行时配置, 需在处理请求和启动解析探测前调用
+func SetSettings(manager *settings.Manager) {
+	runtimeSettings = manager
+}
+
+// userAgent 返回运行时配置中平台的User-Agent, 未配置时返回 fallback
+func userAgent(platform, fallback string) string {
+	if ua := runtimeSettings.Get().UserAgent(platform); ua != "" {
+		return ua
+	}
+	return fallback
+}
+
+// FlagParse 解析功能开关, 可在 platforms 中关闭单个平台的解析
+const FlagParse = "parse"
+
+// checkPlatformEnabled 平台的解析开关对当前请求方关闭时返回 ErrPlatformDisabled
+func checkPlatformEnabled(ctx context.Context, source string) error {
+	if !runtimeSettings.Get().FlagEnabled(FlagParse, source, settings.Subject(ctx)) {
+		return fmt.Errorf("%w: %s", ErrPlatformDisabled, source)
+	}
+	return nil
+}
+
+// newParseClient 创建请求平台接口的resty客户端, 共享连接池和域名策略
+func newParseClient() *resty.Client {
+	return httpClient.Resty().SetTimeout(parseRequestTimeout)
+}
+
+// redirectLocation 请求链接但不跟随跳转, 返回跳转地址, 使用 source 平台配置的User-Agent
+func redirectLocation(ctx context.Context, source, requestUrl string) (string, error) {
+	client := newParseClient().
+		SetRedirectPolicy(resty.NoRedirectPolicy())
+	res, err := client.R().
+		SetContext(ctx).
+		SetHeader(HttpHeaderUserAgent, userAgent(source, DefaultUserAgent)).
+		Get(requestUrl)
+	if err != nil && !errors.Is(err, resty.ErrAutoRedirectDisabled) {
+		return "", UpstreamRequestError("request redirect", err)
+	}
+	if res == nil {
+		return "", fmt.Errorf("%w: empty redirect response", ErrUpstreamBlocked)
+	}
+	location := res.Header().Get("Location")
+	if location == "" {
+		if res.StatusCode() >= 400 {
+			return "", UpstreamStatusError("redirect", res.StatusCode())
+		}
+		// 短链已失效时平台通常直接返回首页而不是跳转
+		return "", fmt.Errorf("%w: no redirect location, status: %s", ErrInvalidLink, res.Status())
+	}
+	return location, nil
+}
+
+// videoSourceInfoMapping 视频渠道映射信息
+var videoSourceInfoMapping = map[string]models.VideoSourceInfo{
+	SourceDouYin: {
+		VideoShareUrlDomain: []string{"v.douyin.com", "www.iesdouyin.com", "www.douyin.com", "douyin.com"},
+		VideoShareUrlParser: douYin{},
+		VideoIdParser:       douYin{},
+	},
+	SourceXiaoHongShu: {
+		VideoShareUrlDomain: []string{"xhslink.com", "www.xiaohongshu.com", "xiaohongshu.com"},
+		VideoShareUrlParser: xiaoHongShu{},
+		VideoIdParser:       xiaoHongShu{},
+	},
+	SourceBiliBili: {
+		VideoShareUrlDomain: []string{"b23.tv", "www.bilibili.com", "bilibili.com", "m.bilibili.com"},
+		VideoShareUrlParser: biliBili{},
+		VideoIdParser:       biliBili{},
+	},
+	SourceKuaiShou: {
+		VideoShareUrlDomain: []string{"v.kuaishou.com", "www.kuaishou.com", "kuaishou.com", "v.m.chenzhongtech.com"},
+		VideoShareUrlParser: kuaiShou{},
+	},
+	SourceWeiBo: {
+		VideoShareUrlDomain: []string{"video.weibo.com", "h5.video.weibo.com", "weibo.com", "www.weibo.com", "m.weibo.cn"},
+		VideoShareUrlParser: weiBo{},
+		VideoIdParser:       weiBo{},
+	},
+	SourceXiGua: {
+		VideoShareUrlDomain: []string{"v.ixigua.com", "www.ixigua.com", "ixigua.com", "m.ixigua.com"},
+		VideoShareUrlParser: xiGua{},
+		VideoIdParser:       xiGua{},
+	},
+}
+
+// ParseVideoShareUrlByRegexp 从分享文案中提取链接并解析
+func ParseVideoShareUrlByRegexp(ctx context.Context, shareMsg string) (*models.VideoParseInfo, error) {
+	videoShareUrl, err := utils.RegexpMatchUrlFromString(shareMsg)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidLink, err)
+	}
+	return ParseVideoShareUrl(ctx, videoShareUrl)
+}
+
+// ParseVideoShareUrl 根据分享链接的域名选择对应渠道解析
+func ParseVideoShareUrl(ctx context.Context, shareUrl string) (*models.VideoParseInfo, error) {
+	source, err := videoSourceByShareUrl(shareUrl)
+	if err != nil {
+		return nil, err
+	}
+	parser := videoSourceInfoMapping[source].VideoShareUrlParser
+	if parser == nil {
+		return nil, fmt.Errorf("%w: source %s has no video share url parser", ErrUnsupportedPlatform, source)
+	}
+	if err := checkPlatformEnabled(ctx, source); err != nil {
+		return nil, err
+	}
+	return safeParse(ctx, source, func(ctx context.Context) (*models.VideoParseInfo, error) {
+		return parser.ParseShareUrl(ctx, shareUrl)
+	})
+}
+
+// ParseVideoId 根据渠道和视频id解析
+func ParseVideoId(ctx context.Context, source, videoId string) (*models.VideoParseInfo, error) {
+	if videoId == "" || source == "" {
+		return nil, fmt.Errorf("%w: video source or video id is empty", ErrInvalidLink)
+	}
+	sourceInfo, ok := videoSourceInfoMapping[source]
+	if !ok {
+		return nil, fmt.Errorf("%w: source %s", ErrUnsupportedPlatform, source)
+	}
+	if sourceInfo.VideoIdParser == nil {
+		return nil, fmt.Errorf("%w: source %s has no video id parser", ErrUnsupportedPlatform, source)
+	}
+	if err := checkPlatformEnabled(ctx, source); err != nil {
+		return nil, err
+	}
+	return safeParse(ctx, source, func(ctx context.Context) (*models.VideoParseInfo, error) {
+		return sourceInfo.VideoIdParser.ParseVideoID(ctx, videoId)
+	})
+}
+
+// BatchParseVideoId 根据视频id批量解析, 单条失败记录在对应的 BatchParseItem 中
+func BatchParseVideoId(ctx context.Context, source string, videoIds []string) (map[string]models.BatchParseItem, error) {
+	if len(videoIds) == 0 || source == "" {
+		return nil, fmt.Errorf("%w: batch parse params empty", ErrInvalidLink)
+	}
+	sourceInfo, ok := videoSourceInfoMapping[source]
+	if !ok {
+		return nil, fmt.Errorf("%w: source %s", ErrUnsupportedPlatform, source)
+	}
+	if sourceInfo.VideoIdParser == nil {
+		return nil, fmt.Errorf("%w: source %s has no video id parser", ErrUnsupportedPlatform, source)
+	}
+	if err := checkPlatformEnabled(ctx, source); err != nil {
+		return nil, err
+	}
+
+	var (
+		wg          sync.WaitGroup
+		mu          sync.Mutex
+		parseResult = make(map[string]models.BatchParseItem, len(videoIds))
+	)
+	for _, videoId := range videoIds {
+		wg.Add(1)
+		go func(videoId string) {
+			defer wg.Done()
+			parseInfo, err := safeParse(ctx, source, func(ctx context.Context) (*models.VideoParseInfo, error) {
+				return sourceInfo.VideoIdParser.ParseVideoID(ctx, videoId)
+			})
+			mu.Lock()
+			parseResult[videoId] = models.BatchParseItem{ParseInfo: parseInfo, Error: err}
+			mu.Unlock()
+		}(videoId)
+	}
+	wg.Wait()
+	return parseResult, nil
+}
+
+// safeParse 执行单个渠道的解析, 将解析过程中的panic转换为错误, 避免某个平台页面结构变化影响整个服务
+func safeParse(ctx context.Context, source string, parse func(ctx context.Context) (*models.VideoParseInfo, error)) (parseInfo *models.VideoParseInfo, err error) {
+	start := time.Now()
+	ctx, span := tracing.Start(ctx, "parse "+source, attribute.String("parse.source", source))
+	defer func() {
+		if r := recover(); r != nil {
+			parseInfo, err = nil, fmt.Errorf("%w: %s parser panic: %v", ErrParseFailed, source, r)
+		}
+		span.SetAttributes(attribute.String("parse.result", errorKind(err)))
+		tracing.End(span, err)
+		metrics.ObserveParse(source, errorKind(err), time.Since(start))
+	}()
+	return parse(ctx)
+}
+
+// videoSourceByShareUrl 根据分享链接域名判断视频渠道
+func videoSourceByShareUrl(shareUrl string) (string, error) {
+	u, err := url.Parse(shareUrl)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidLink, err)
+	}
+	host := strings.ToLower(u.Hostname())
+	for source, info := range videoSourceInfoMapping {
+		for _, domain := range info.VideoShareUrlDomain {
+			if host == domain {
+				return source, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("%w: share url [%s] not have source config", ErrUnsupportedPlatform, shareUrl)
+}
@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"mime"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/models"
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/upstream"
+)
+
+// fakePlatforms 将所有平台域名的请求转发到本地TLS服务, 按 域名+路径 匹配 routes, 测试结束后恢复出站客户端
+func fakePlatforms(t *testing.T, routes map[string]http.HandlerFunc) {
+	t.Helper()
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route, ok := routes[r.Host+r.URL.Path]
+		if !ok {
+			t.Logf("unexpected request: %s", r.URL)
+			http.NotFound(w, r)
+			return
+		}
+		route(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	// 证书不包含平台域名, 拨号时忽略目标地址并跳过证书校验
+	transport := server.Client().Transport.(*http.Transport).Clone()
+	transport.TLSClientConfig.InsecureSkipVerify = true
+	transport.DialContext = func(ctx context.Context, network, _ string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, network, server.Listener.Addr().String())
+	}
+	previous := httpClient
+	SetHTTPClient(upstream.New(upstream.Config{Policies: HostPolicies(nil), Transport: transport}))
+	t.Cleanup(func() { SetHTTPClient(previous) })
+}
+
+// serveFixture 返回 testdata 中的文件, 按扩展名设置 Content-Type
+func serveFixture(t *testing.T, name string) http.HandlerFunc {
+	t.Helper()
+	body, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", mime.TypeByExtension(filepath.Ext(name)))
+		w.Write(body)
+	}
+}
+
+// redirectTo 返回跳转到 location 的短链响应
+func redirectTo(location string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, location, http.StatusFound)
+	}
+}
+
+func TestParseVideoShareUrlByRegexpDispatchesBySource(t *testing.T) {
+	fakePlatforms(t, map[string]http.HandlerFunc{
+		"v.douyin.com/iRNBho6u/":                            redirectTo("https://www.iesdouyin.com/share/video/7301234567890123456/?region=CN&mid=7301234"),
+		"www.iesdouyin.com/share/video/7301234567890123456": serveFixture(t, "douyin_video.html"),
+		"aweme.snssdk.com/aweme/v1/play/":                   redirectTo("https://v26-web.douyinvod.com/0a1b2c/video.mp4"),
+	})
+
+	shareMsg := "7.43 复制打开抖音, 看看【海边的阿杰的作品】傍晚的海边 # 日落 https://v.douyin.com/iRNBho6u/ 02/15 a@a.Gv"
+	parseInfo, err := ParseVideoShareUrlByRegexp(context.Background(), shareMsg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parseInfo.Title != "傍晚的海边 #日落" {
+		t.Errorf("title = %q", parseInfo.Title)
+	}
+	if parseInfo.VideoUrl != "https://v26-web.douyinvod.com/0a1b2c/video.mp4" {
+		t.Errorf("video url = %q", parseInfo.VideoUrl)
+	}
+}
+
+func TestParseVideoShareUrlUnsupportedPlatform(t *testing.T) {
+	_, err := ParseVideoShareUrl(context.Background(), "https://www.example.com/video/1")
+	if !errors.Is(err, ErrUnsupportedPlatform) {
+		t.Fatalf("err = %v, want ErrUnsupportedPlatform", err)
+	}
+}
+
+func TestParseVideoIdUnsupportedSource(t *testing.T) {
+	_, err := ParseVideoId(context.Background(), "tiktok", "1")
+	if !errors.Is(err, ErrUnsupportedPlatform) {
+		t.Fatalf("err = %v, want ErrUnsupportedPlatform", err)
+	}
+	// 快手没有id解析方法
+	_, err = ParseVideoId(context.Background(), SourceKuaiShou, "3xabc")
+	if !errors.Is(err, ErrUnsupportedPlatform) {
+		t.Fatalf("err = %v, want ErrUnsupportedPlatform", err)
+	}
+}
+
+// panicParser 模拟页面结构变化导致解析时panic的平台
+type panicParser struct{}
+
+func (panicParser) ParseShareUrl(ctx context.Context, shareUrl string) (*models.VideoParseInfo, error) {
+	var item map[string]any
+	return &models.VideoParseInfo{Title: item["desc"].(string)}, nil
+}
+
+func (p panicParser) ParseVideoID(ctx context.Context, videoId string) (*models.VideoParseInfo, error) {
+	return p.ParseShareUrl(ctx, videoId)
+}
+
+func TestParserPanicBecomesError(t *testing.T) {
+	const source = "panicky"
+	videoSourceInfoMapping[source] = models.VideoSourceInfo{
+		VideoShareUrlDomain: []string{"panicky.example.com"},
+		VideoShareUrlParser: panicParser{},
+		VideoIdParser:       panicParser{},
+	}
+	t.Cleanup(func() { delete(videoSourceInfoMapping, source) })
+
+	parseInfo, err := ParseVideoShareUrl(context.Background(), "https://panicky.example.com/video/1")
+	if parseInfo != nil || !errors.Is(err, ErrParseFailed) {
+		t.Fatalf("ParseVideoShareUrl = %+v, %v, want ErrParseFailed", parseInfo, err)
+	}
+	parseInfo, err = ParseVideoId(context.Background(), source, "1")
+	if parseInfo != nil || !errors.Is(err, ErrParseFailed) {
+		t.Fatalf("ParseVideoId = %+v, %v, want ErrParseFailed", parseInfo, err)
+	}
+	// 批量解析中单条panic只影响该条结果
+	results, err := BatchParseVideoId(context.Background(), source, []string{"1", "2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("results = %v", results)
+	}
+	for videoId, item := range results {
+		if !errors.Is(item.Error, ErrParseFailed) {
+			t.Errorf("batch item %s error = %v, want ErrParseFailed", videoId, item.Error)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+)
+
+// pdfCommandTimeout 外部PDF命令(pdftoppm/pdftotext)的最长执行时间
+const pdfCommandTimeout = 2 * time.Minute
+
+// MergePDF 按顺序合并多个PDF文件
+func MergePDF(inFiles []string, outFile string) error {
+	if len(inFiles) < 2 {
+		return fmt.Errorf("至少需要两个PDF文件才能合并")
+	}
+	if err := api.MergeCreateFile(inFiles, outFile, false, nil); err != nil {
+		return fmt.Errorf("合并PDF失败: %v", err)
+	}
+	return nil
+}
+
+// SplitPDF 按页码范围拆分PDF, 每个范围(如 "1-3"、"5"、"7-")生成一个文件, 返回生成的文件路径
+func SplitPDF(inFile, outDir string, pageRanges []string) ([]string, error) {
+	if len(pageRanges) == 0 {
+		return nil, fmt.Errorf("缺少页码范围")
+	}
+	outFiles := make([]string, 0, len(pageRanges))
+	for i, pageRange := range pageRanges {
+		selectedPages, err := api.ParsePageSelection(pageRange)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q: %v", ErrInvalidPageRange, pageRange, err)
+		}
+		outFile := filepath.Join(outDir, fmt.Sprintf("split-%d.pdf", i+1))
+		if err := api.TrimFile(inFile, outFile, selectedPages, nil); err != nil {
+			return nil, fmt.Errorf("拆分PDF失败 (范围 %s): %v", pageRange, err)
+		}
+		outFiles = append(outFiles, outFile)
+	}
+	return outFiles, nil
+}
+
+// CompressPDF 优化PDF结构以减小文件体积
+func CompressPDF(inFile, outFile string) error {
+	if err := api.OptimizeFile(inFile, outFile, nil); err != nil {
+		return fmt.Errorf("压缩PDF失败: %v", err)
+	}
+	return nil
+}
+
+// WatermarkPDF 为PDF添加文字水印, pages 为空时处理所有页面
+func WatermarkPDF(inFile, outFile, text, pages string, opacity float64) error {
+	if text == "" {
+		return fmt.Errorf("水印文字不能为空")
+	}
+	if opacity <= 0 || opacity > 1 {
+		opacity = 0.3
+	}
+	var selectedPages []string
+	if pages != "" {
+		var err error
+		if selectedPages, err = api.ParsePageSelection(pages); err != nil {
+			return fmt.Errorf("%w: %q: %v", ErrInvalidPageRange, pages, err)
+		}
+	}
+	desc := fmt.Sprintf("scalefactor:0.6 rel, rotation:45, opacity:%.2f", opacity)
+	if err := api.AddTextWatermarksFile(inFile, outFile, selectedPages, false, text, desc, nil); err != nil {
+		return fmt.Errorf("添加水印失败: %v", err)
+	}
+	return nil
+}
+
+// PDFPagesToImages 使用pdftoppm将PDF页面渲染为图片, firstPage/lastPage 为0时表示不限制
+func PDFPagesToImages(ctx context.Context, pdftoppm, inFile, outDir, format string, dpi, firstPage, lastPage int) ([]string, error) {
+	var formatFlag, ext string
+	switch format {
+	case "", "png":
+		formatFlag, ext = "-png", "png"
+	case "jpg", "jpeg":
+		formatFlag, ext = "-jpeg", "jpg"
+	default:
+		return nil, fmt.Errorf("不支持的图片格式: %s", format)
+	}
+	if dpi <= 0 || dpi > 600 {
+		dpi = 150
+	}
+
+	args := []string{formatFlag, "-r", strconv.Itoa(dpi)}
+	if firstPage > 0 {
+		args = append(args, "-f", strconv.Itoa(firstPage))
+	}
+	if lastPage > 0 {
+		args = append(args, "-l", strconv.Itoa(lastPage))
+	}
+	prefix := filepath.Join(outDir, "page")
+	args = append(args, inFile, prefix)
+
+	ctx, cancel := context.WithTimeout(ctx, pdfCommandTimeout)
+	defer cancel()
+	if output, err := exec.CommandContext(ctx, pdftoppm, args...).CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("pdftoppm执行失败: %v, 输出: %s", err, string(output))
+	}
+
+	// pdftoppm 输出形如 page-01.png 的文件, 序号位数随总页数变化
+	images, err := filepath.Glob(prefix + "-*." + ext)
+	if err != nil {
+		return nil, err
+	}
+	if len(images) == 0 {
+		return nil, fmt.Errorf("%w: 未生成任何图片", ErrInvalidPageRange)
+	}
+	sort.Strings(images)
+	return images, nil
+}
+
+// ExtractPDFText 使用pdftotext提取PDF中的纯文本并写入 outFile
+func ExtractPDFText(ctx context.Context, pdftotext, inFile, outFile string) error {
+	ctx, cancel := context.WithTimeout(ctx, pdfCommandTimeout)
+	defer cancel()
+	if output, err := exec.CommandContext(ctx, pdftotext, "-layout", "-enc", "UTF-8", inFile, outFile).CombinedOutput(); err != nil {
+		return fmt.Errorf("pdftotext执行失败: %v, 输出: %s", err, strings.TrimSpace(string(output)))
+	}
+	if _, err := os.Stat(outFile); err != nil {
+		return fmt.Errorf("未生成文本文件: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+package service
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestSplitPDFInvalidPageRange(t *testing.T) {
+	dir := t.TempDir()
+	_, err := SplitPDF(filepath.Join(dir, "in.pdf"), dir, []string{"1-x"})
+	if !errors.Is(err, ErrInvalidPageRange) {
+		t.Fatalf("err = %v, want ErrInvalidPageRange", err)
+	}
+}
+
+func TestWatermarkPDFInvalidPageRange(t *testing.T) {
+	dir := t.TempDir()
+	err := WatermarkPDF(filepath.Join(dir, "in.pdf"), filepath.Join(dir, "out.pdf"), "机密", "a-b", 0.3)
+	if !errors.Is(err, ErrInvalidPageRange) {
+		t.Fatalf("err = %v, want ErrInvalidPageRange", err)
+	}
+}
@@ -0,0 +1,38 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/metrics"
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/settings"
+)
+
+// MuxToMP4 使用ffmpeg将分离的视频流和音频流(如B站DASH)合并为MP4
+//
+// videoFilter 为空且转码参数不需要缩放时直接复制视频流, 否则应用过滤器并按转码参数重新编码为H.264
+func MuxToMP4(ctx context.Context, ffmpeg, videoFile, audioFile, outFile, videoFilter string, profile settings.TranscodeProfile) error {
+	args := []string{"-hide_banner", "-loglevel", "error", "-i", videoFile, "-i", audioFile, "-map", "0:v:0", "-map", "1:a:0"}
+	if videoFilter = profile.VideoFilter(videoFilter); videoFilter != "" {
+		args = append(args, "-vf", videoFilter)
+		args = append(args, profile.EncodeArgs()...)
+	} else {
+		args = append(args, "-c:v", "copy")
+	}
+	args = append(args, "-c:a", "aac", "-movflags", "+faststart", "-y", outFile)
+
+	start := time.Now()
+	output, err := exec.CommandContext(ctx, ffmpeg, args...).CombinedOutput()
+	metrics.ObserveFFmpeg("mux", start, err)
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("音视频合并超时")
+		}
+		return fmt.Errorf("FFmpeg合并音视频失败: %v, 输出: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/metrics"
+)
+
+// watermarkParamsPattern 过滤器参数允许的字符, 防止注入额外的ffmpeg过滤器
+var watermarkParamsPattern = regexp.MustCompile(`^[\w:=.+\-*/()]+$`)
+
+// WatermarkFilter 去水印所使用的ffmpeg过滤器, 仅支持 crop 和 delogo
+type WatermarkFilter struct {
+	Name   string // crop 或 delogo
+	Params string // 过滤器参数, 如 x=10:y=10:w=120:h=50
+}
+
+// String 返回ffmpeg -vf 参数格式
+func (f WatermarkFilter) String() string {
+	return f.Name + "=" + f.Params
+}
+
+// ParseWatermarkFilters 解析各平台去水印过滤器配置
+//
+// 格式为 平台=过滤器:参数, 多个平台以分号分隔, 例如:
+//
+//	douyin=delogo:x=10:y=10:w=160:h=60;xiaohongshu=crop:iw:ih-80:0:0
+func ParseWatermarkFilters(spec string) (map[string]WatermarkFilter, error) {
+	filters := make(map[string]WatermarkFilter)
+	for _, item := range strings.Split(spec, ";") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		platform, filter, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid watermark filter %q", item)
+		}
+		name, params, _ := strings.Cut(filter, ":")
+		if name != "crop" && name != "delogo" {
+			return nil, fmt.Errorf("unsupported watermark filter %q for %s", name, platform)
+		}
+		if !watermarkParamsPattern.MatchString(params) {
+			return nil, fmt.Errorf("invalid watermark filter params %q for %s", params, platform)
+		}
+		filters[strings.TrimSpace(platform)] = WatermarkFilter{Name: name, Params: params}
+	}
+	return filters, nil
+}
+
+// CleanImage 使用ffmpeg对图片应用去水印过滤器, 输出JPEG, 请求取消时终止处理
+func CleanImage(ctx context.Context, ffmpeg string, data []byte, filter WatermarkFilter) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, imageEncodeTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, ffmpeg,
+		"-hide_banner", "-loglevel", "error",
+		"-f", "image2pipe", "-i", "pipe:0",
+		"-vf", filter.String(),
+		"-frames:v", "1",
+		"-c:v", "mjpeg", "-q:v", "2",
+		"-f", "image2pipe", "pipe:1")
+	cmd.Stdin = bytes.NewReader(data)
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+	start := time.Now()
+	err := cmd.Run()
+	metrics.ObserveFFmpeg("image_clean", start, err)
+	if err != nil {
+		return nil, fmt.Errorf("FFmpeg去水印失败: %v, 输出: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/models"
+	"github.com/tidwall/gjson"
+)
+
+// weiBoVideoIdReg 视频id, 形如 1034:4xxxxxxxxxxxxxxx
+var weiBoVideoIdReg = regexp.MustCompile(`\d+:\d+`)
+
+// weiBoComponentApi 视频播放信息接口
+const weiBoComponentApi = "https://h5.video.weibo.com/api/component"
+
+type weiBo struct{}
+
+// ParseShareUrl 解析微博视频分享链接, 支持 video.weibo.com/show?fid= 和 weibo.com/tv/show/ 格式
+func (w weiBo) ParseShareUrl(ctx context.Context, shareUrl string) (*models.VideoParseInfo, error) {
+	var videoId string
+	if u, err := url.Parse(shareUrl); err == nil {
+		videoId = u.Query().Get("fid")
+	}
+	if videoId == "" {
+		videoId = weiBoVideoIdReg.FindString(shareUrl)
+	}
+	if videoId == "" {
+		return nil, fmt.Errorf("%w: weibo parse video id from url: %s", ErrInvalidLink, shareUrl)
+	}
+	return w.ParseVideoID(ctx, videoId)
+}
+
+// ParseVideoID 根据视频id(如 1034:4xxxxxxxxxxxxxxx)解析
+func (w weiBo) ParseVideoID(ctx context.Context, videoId string) (*models.VideoParseInfo, error) {
+	client := newParseClient()
+	res, err := client.R().
+		SetContext(ctx).
+		SetHeader(HttpHeaderUserAgent, userAgent(SourceWeiBo, DesktopUserAgent)).
+		SetHeader(HttpHeaderReferer, "https://h5.video.weibo.com/show/"+videoId).
+		SetQueryParam("page", "/show/"+videoId).
+		SetFormData(map[string]string{
+			"data": fmt.Sprintf(`{"Component_Play_Playinfo":{"oid":"%s"}}`, videoId),
+		}).
+		Post(weiBoComponentApi)
+	if err != nil {
+		return nil, UpstreamRequestError("weibo request component api", err)
+	}
+	if res.StatusCode() != 200 {
+		return nil, UpstreamStatusError("weibo component api", res.StatusCode())
+	}
+
+	playInfo := gjson.GetBytes(res.Body(), "data.Component_Play_Playinfo")
+	// 视频被删除时字段不存在或为null
+	if !playInfo.IsObject() {
+		msg := gjson.GetBytes(res.Body(), "msg").String()
+		return nil, fmt.Errorf("%w: weibo video %s: %s", ErrContentUnavailable, videoId, msg)
+	}
+
+	parseInfo := &models.VideoParseInfo{
+		Title:    playInfo.Get("title").String(),
+		CoverUrl: weiBoAbsoluteUrl(playInfo.Get("cover_image").String()),
+	}
+	parseInfo.Author.Uid = playInfo.Get("author_id").String()
+	parseInfo.Author.Name = playInfo.Get("author").String()
+	parseInfo.Author.Avatar = weiBoAbsoluteUrl(playInfo.Get("avatar").String())
+
+	// urls 为 清晰度 => 地址 的映射, 按接口返回顺序第一个为最高清晰度
+	playInfo.Get("urls").ForEach(func(key, value gjson.Result) bool {
+		parseInfo.VideoUrl = weiBoAbsoluteUrl(value.String())
+		return parseInfo.VideoUrl == ""
+	})
+	if parseInfo.VideoUrl == "" {
+		return nil, fmt.Errorf("%w: weibo parse video url", ErrParseFailed)
+	}
+	return parseInfo, nil
+}
+
+// weiBoAbsoluteUrl 补全接口返回的协议相对地址
+func weiBoAbsoluteUrl(u string) string {
+	if strings.HasPrefix(u, "//") {
+		return "https:" + u
+	}
+	return u
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestWeiBoParseShareUrl(t *testing.T) {
+	fakePlatforms(t, map[string]http.HandlerFunc{
+		"h5.video.weibo.com/api/component": func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost {
+				t.Errorf("method = %s, want POST", r.Method)
+			}
+			if want := `{"Component_Play_Playinfo":{"oid":"1034:4951234567890123"}}`; r.FormValue("data") != want {
+				t.Errorf("data = %q, want %q", r.FormValue("data"), want)
+			}
+			serveFixture(t, "weibo_component.json")(w, r)
+		},
+	})
+
+	parseInfo, err := weiBo{}.ParseShareUrl(context.Background(), "https://weibo.com/tv/show/1034:4951234567890123?from=old_pc_videoshow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parseInfo.Title != "城市夜景延时摄影" {
+		t.Errorf("title = %q", parseInfo.Title)
+	}
+	// 取第一个(最高)清晰度, 并补全协议
+	if parseInfo.VideoUrl != "https://f.video.weibocdn.com/o0/1080p.mp4?label=mp4_1080p" {
+		t.Errorf("video url = %q", parseInfo.VideoUrl)
+	}
+	if parseInfo.CoverUrl != "https://wx3.sinaimg.cn/orj480/cover.jpg" {
+		t.Errorf("cover url = %q", parseInfo.CoverUrl)
+	}
+	if parseInfo.Author.Uid != "5678901234" || parseInfo.Author.Avatar != "https://tvax1.sinaimg.cn/crop.0.0.180.180.180/abcdef.jpg" {
+		t.Errorf("author = %+v", parseInfo.Author)
+	}
+}
+
+func TestWeiBoParseShareUrlFid(t *testing.T) {
+	fakePlatforms(t, map[string]http.HandlerFunc{
+		"h5.video.weibo.com/api/component": serveFixture(t, "weibo_component.json"),
+	})
+
+	parseInfo, err := weiBo{}.ParseShareUrl(context.Background(), "https://video.weibo.com/show?fid=1034:4951234567890123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parseInfo.VideoUrl == "" {
+		t.Error("video url is empty")
+	}
+}
+
+func TestWeiBoParseVideoIDUnavailable(t *testing.T) {
+	fakePlatforms(t, map[string]http.HandlerFunc{
+		"h5.video.weibo.com/api/component": serveFixture(t, "weibo_unavailable.json"),
+	})
+
+	_, err := weiBo{}.ParseVideoID(context.Background(), "1034:4950000000000000")
+	if !errors.Is(err, ErrContentUnavailable) {
+		t.Fatalf("err = %v, want ErrContentUnavailable", err)
+	}
+}
+
+func TestWeiBoParseVideoIDDeleted(t *testing.T) {
+	// 视频被删除时 Component_Play_Playinfo 为null
+	fakePlatforms(t, map[string]http.HandlerFunc{
+		"h5.video.weibo.com/api/component": serveFixture(t, "weibo_deleted.json"),
+	})
+
+	_, err := weiBo{}.ParseVideoID(context.Background(), "1034:4950000000000000")
+	if !errors.Is(err, ErrContentUnavailable) {
+		t.Fatalf("err = %v, want ErrContentUnavailable", err)
+	}
+}
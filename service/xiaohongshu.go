@@ -0,0 +1,162 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/models"
+	"github.com/tidwall/gjson"
+)
+
+var (
+	// xiaoHongShuInitialStateReg 笔记页中保存页面数据的脚本
+	xiaoHongShuInitialStateReg = regexp.MustCompile(`(?s)window\.__INITIAL_STATE__\s*=\s*(.*?)</script>`)
+	// xiaoHongShuNoteIdReg 从笔记页路径中提取笔记id
+	xiaoHongShuNoteIdReg = regexp.MustCompile(`/(?:explore|discovery/item)/([0-9a-zA-Z]+)`)
+	// xiaoHongShuUndefinedReg 初始状态中值位置(冒号、方括号、逗号之后)的 undefined, 避免替换标题等文本中的单词
+	xiaoHongShuUndefinedReg = regexp.MustCompile(`([:\[,])\s*undefined\b`)
+)
+
+const (
+	// xiaoHongShuNoteBaseUrl 笔记页地址
+	xiaoHongShuNoteBaseUrl = "https://www.xiaohongshu.com/explore/"
+	// xiaoHongShuVideoCdnUrl 视频原始文件(无水印)地址前缀
+	xiaoHongShuVideoCdnUrl = "https://sns-video-bd.xhscdn.com/"
+)
+
+type xiaoHongShu struct{}
+
+// ParseShareUrl 解析小红书分享链接, xhslink.com 短链会先跟随跳转
+func (x xiaoHongShu) ParseShareUrl(ctx context.Context, shareUrl string) (*models.VideoParseInfo, error) {
+	noteUrl := shareUrl
+	if u, err := url.Parse(shareUrl); err == nil && u.Hostname() == "xhslink.com" {
+		location, err := redirectLocation(ctx, SourceXiaoHongShu, shareUrl)
+		if err != nil {
+			return nil, fmt.Errorf("xiaohongshu short url redirect: %w", err)
+		}
+		noteUrl = location
+	}
+
+	findRes := xiaoHongShuNoteIdReg.FindStringSubmatch(noteUrl)
+	if len(findRes) < 2 {
+		return nil, fmt.Errorf("%w: xiaohongshu parse note id from url: %s", ErrInvalidLink, noteUrl)
+	}
+	// 保留原链接中的 xsec_token 等参数, 缺少时笔记页可能不返回数据
+	return x.parseNotePage(ctx, noteUrl, findRes[1])
+}
+
+// ParseVideoID 根据笔记id解析
+func (x xiaoHongShu) ParseVideoID(ctx context.Context, videoId string) (*models.VideoParseInfo, error) {
+	return x.parseNotePage(ctx, xiaoHongShuNoteBaseUrl+videoId, videoId)
+}
+
+// parseNotePage 获取笔记页并从初始状态数据中解析笔记信息
+func (x xiaoHongShu) parseNotePage(ctx context.Context, noteUrl, noteId string) (*models.VideoParseInfo, error) {
+	client := newParseClient()
+	res, err := client.R().
+		SetContext(ctx).
+		SetHeader(HttpHeaderUserAgent, userAgent(SourceXiaoHongShu, DesktopUserAgent)).
+		SetHeader(HttpHeaderReferer, "https://www.xiaohongshu.com/").
+		Get(noteUrl)
+	if err != nil {
+		return nil, UpstreamRequestError("xiaohongshu request note page", err)
+	}
+	if res.StatusCode() != 200 {
+		return nil, UpstreamStatusError("xiaohongshu note page", res.StatusCode())
+	}
+
+	findRes := xiaoHongShuInitialStateReg.FindSubmatch(res.Body())
+	if len(findRes) < 2 {
+		return nil, fmt.Errorf("%w: xiaohongshu parse initial state", ErrParseFailed)
+	}
+	// 初始状态是JS对象字面量, 其中作为值的 undefined 需要替换为合法的JSON值
+	initialState := gjson.ParseBytes(xiaoHongShuUndefinedReg.ReplaceAll(findRes[1], []byte("${1}null")))
+
+	note := initialState.Get("note.noteDetailMap." + gjson.Escape(noteId) + ".note")
+	if !note.Exists() || note.Get("noteId").String() == "" {
+		return nil, fmt.Errorf("%w: xiaohongshu note %s", ErrContentUnavailable, noteId)
+	}
+
+	parseInfo := &models.VideoParseInfo{
+		Title: firstNonEmpty(note.Get("title").String(), note.Get("desc").String()),
+	}
+	parseInfo.Author.Uid = note.Get("user.userId").String()
+	parseInfo.Author.Name = note.Get("user.nickname").String()
+	parseInfo.Author.Avatar = note.Get("user.avatar").String()
+
+	imageList := note.Get("imageList").Array()
+	if len(imageList) > 0 {
+		parseInfo.CoverUrl = xiaoHongShuImageUrl(imageList[0])
+	}
+
+	if note.Get("type").String() == "video" {
+		videoUrl := xiaoHongShuVideoUrl(note.Get("video"))
+		if videoUrl == "" {
+			return nil, fmt.Errorf("%w: xiaohongshu parse video url", ErrParseFailed)
+		}
+		parseInfo.VideoUrl = videoUrl
+		return parseInfo, nil
+	}
+
+	// 图文笔记, 实况图片额外返回视频片段
+	for _, image := range imageList {
+		imageUrl := xiaoHongShuImageUrl(image)
+		if imageUrl == "" {
+			continue
+		}
+		parseInfo.Images = append(parseInfo.Images, imageUrl)
+		if image.Get("livePhoto").Bool() {
+			if liveUrl := xiaoHongShuStreamUrl(image.Get("stream")); liveUrl != "" {
+				parseInfo.LivePhotos = append(parseInfo.LivePhotos, models.LivePhoto{
+					Index:    len(parseInfo.Images) - 1,
+					VideoUrl: liveUrl,
+				})
+			}
+		}
+	}
+	if len(parseInfo.Images) == 0 {
+		return nil, fmt.Errorf("%w: xiaohongshu parse images", ErrParseFailed)
+	}
+	return parseInfo, nil
+}
+
+// xiaoHongShuImageUrl 返回图片的默认(无水印)地址
+func xiaoHongShuImageUrl(image gjson.Result) string {
+	imageUrl := image.Get("urlDefault").String()
+	if imageUrl == "" {
+		// 旧版页面数据中图片地址位于 infoList, WB_DFT 为默认尺寸
+		for _, info := range image.Get("infoList").Array() {
+			if info.Get("imageScene").String() == "WB_DFT" {
+				imageUrl = info.Get("url").String()
+				break
+			}
+		}
+	}
+	if imageUrl == "" {
+		imageUrl = image.Get("url").String()
+	}
+	return strings.Replace(imageUrl, "http://", "https://", 1)
+}
+
+// xiaoHongShuVideoUrl 优先使用原始文件地址, 其次使用转码流地址
+func xiaoHongShuVideoUrl(video gjson.Result) string {
+	if originVideoKey := video.Get("consumer.originVideoKey").String(); originVideoKey != "" {
+		return xiaoHongShuVideoCdnUrl + originVideoKey
+	}
+	return xiaoHongShuStreamUrl(video.Get("media.stream"))
+}
+
+// xiaoHongShuStreamUrl 按 h264、h265、av1 的顺序返回第一个可用的流地址
+func xiaoHongShuStreamUrl(stream gjson.Result) string {
+	for _, codec := range []string{"h264", "h265", "av1"} {
+		for _, item := range stream.Get(codec).Array() {
+			if masterUrl := firstNonEmpty(item.Get("masterUrl").String(), item.Get("backupUrls.0").String()); masterUrl != "" {
+				return masterUrl
+			}
+		}
+	}
+	return ""
+}
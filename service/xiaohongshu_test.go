@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/models"
+)
+
+func TestXiaoHongShuParseVideoNote(t *testing.T) {
+	fakePlatforms(t, map[string]http.HandlerFunc{
+		"www.xiaohongshu.com/explore/6551a2b3000000001f03c4d5": serveFixture(t, "xiaohongshu_video.html"),
+	})
+
+	parseInfo, err := xiaoHongShu{}.ParseVideoID(context.Background(), "6551a2b3000000001f03c4d5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 字符串中的 undefined 不应被替换
+	if parseInfo.Title != "undefined 不是 bug 是 feature" {
+		t.Errorf("title = %q", parseInfo.Title)
+	}
+	// 优先使用原始文件地址
+	if parseInfo.VideoUrl != "https://sns-video-bd.xhscdn.com/pre_post/1040g0cg30rn0abc" {
+		t.Errorf("video url = %q", parseInfo.VideoUrl)
+	}
+	if parseInfo.CoverUrl != "https://sns-webpic-qc.xhscdn.com/202311/cover!nd_dft_wlteh_webp_3" {
+		t.Errorf("cover url = %q", parseInfo.CoverUrl)
+	}
+	if parseInfo.Author.Uid != "5f0c1d2e000000000101a2b3" || parseInfo.Author.Name != "码农小张" {
+		t.Errorf("author = %+v", parseInfo.Author)
+	}
+	if len(parseInfo.Images) != 0 {
+		t.Errorf("images = %v, want none for video note", parseInfo.Images)
+	}
+}
+
+func TestXiaoHongShuParseImageNoteWithLivePhotos(t *testing.T) {
+	fakePlatforms(t, map[string]http.HandlerFunc{
+		"xhslink.com/a/Bc1dE2fG": redirectTo("https://www.xiaohongshu.com/discovery/item/6560b1c2000000003203d4e5?xsec_token=AB12cd&xsec_source=app_share"),
+		"www.xiaohongshu.com/discovery/item/6560b1c2000000003203d4e5": func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("xsec_token") != "AB12cd" {
+				t.Errorf("xsec_token = %q, want the one from the short link", r.URL.Query().Get("xsec_token"))
+			}
+			serveFixture(t, "xiaohongshu_images.html")(w, r)
+		},
+	})
+
+	parseInfo, err := xiaoHongShu{}.ParseShareUrl(context.Background(), "https://xhslink.com/a/Bc1dE2fG")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 没有标题时使用正文
+	if parseInfo.Title != "秋天的公园" {
+		t.Errorf("title = %q", parseInfo.Title)
+	}
+	wantImages := []string{
+		"https://sns-webpic-qc.xhscdn.com/202311/1!nd_dft_wlteh_webp_3",
+		"https://sns-webpic-qc.xhscdn.com/202311/2!nd_dft_wlteh_webp_3",
+		"https://sns-webpic-qc.xhscdn.com/202311/3!nd_dft_wlteh_webp_3",
+	}
+	if len(parseInfo.Images) != len(wantImages) {
+		t.Fatalf("images = %v", parseInfo.Images)
+	}
+	for i, want := range wantImages {
+		if parseInfo.Images[i] != want {
+			t.Errorf("images[%d] = %q, want %q", i, parseInfo.Images[i], want)
+		}
+	}
+	wantLive := models.LivePhoto{Index: 2, VideoUrl: "https://sns-video-hw.xhscdn.com/stream/live/3_h265.mp4"}
+	if len(parseInfo.LivePhotos) != 1 || parseInfo.LivePhotos[0] != wantLive {
+		t.Errorf("live photos = %+v, want [%+v]", parseInfo.LivePhotos, wantLive)
+	}
+	if parseInfo.VideoUrl != "" {
+		t.Errorf("video url = %q, want empty for image note", parseInfo.VideoUrl)
+	}
+}
+
+func TestXiaoHongShuParseUnavailableNote(t *testing.T) {
+	fakePlatforms(t, map[string]http.HandlerFunc{
+		"www.xiaohongshu.com/explore/6551a2b3000000001f03c4d6": serveFixture(t, "xiaohongshu_unavailable.html"),
+	})
+
+	_, err := xiaoHongShu{}.ParseVideoID(context.Background(), "6551a2b3000000001f03c4d6")
+	if !errors.Is(err, ErrContentUnavailable) {
+		t.Fatalf("err = %v, want ErrContentUnavailable", err)
+	}
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/models"
+)
+
+// xiGuaVideoIdReg 从视频页路径中提取视频id
+var xiGuaVideoIdReg = regexp.MustCompile(`/(?:video/)?(\d{10,})`)
+
+// xiGuaShareBaseUrl 视频分享页地址, 与抖音分享页结构相同
+const xiGuaShareBaseUrl = "https://m.ixigua.com/douyin/share/video/"
+
+type xiGua struct{}
+
+// ParseShareUrl 解析西瓜视频分享链接, v.ixigua.com 短链会先跟随跳转
+func (x xiGua) ParseShareUrl(ctx context.Context, shareUrl string) (*models.VideoParseInfo, error) {
+	videoUrl := shareUrl
+	if u, err := url.Parse(shareUrl); err == nil && u.Hostname() == "v.ixigua.com" {
+		location, err := redirectLocation(ctx, SourceXiGua, shareUrl)
+		if err != nil {
+			return nil, fmt.Errorf("xigua short url redirect: %w", err)
+		}
+		videoUrl = location
+	}
+
+	u, err := url.Parse(videoUrl)
+	if err != nil {
+		return nil, fmt.Errorf("%w: xigua parse url: %v", ErrInvalidLink, err)
+	}
+	findRes := xiGuaVideoIdReg.FindStringSubmatch(u.Path)
+	if len(findRes) < 2 {
+		return nil, fmt.Errorf("%w: xigua parse video id from url: %s", ErrInvalidLink, videoUrl)
+	}
+	return x.ParseVideoID(ctx, findRes[1])
+}
+
+// ParseVideoID 根据视频id解析
+func (x xiGua) ParseVideoID(ctx context.Context, videoId string) (*models.VideoParseInfo, error) {
+	client := newParseClient()
+	res, err := client.R().
+		SetContext(ctx).
+		SetHeader(HttpHeaderUserAgent, userAgent(SourceXiGua, DefaultUserAgent)).
+		SetQueryParams(map[string]string{
+			"aweme_type":  "107",
+			"schema_type": "1",
+			"app":         "aweme",
+		}).
+		Get(xiGuaShareBaseUrl + videoId)
+	if err != nil {
+		return nil, UpstreamRequestError("xigua request share page", err)
+	}
+	if res.StatusCode() != 200 {
+		return nil, UpstreamStatusError("xigua share page", res.StatusCode())
+	}
+
+	videoInfoRes, err := routerDataVideoInfo(res.Body())
+	if err != nil {
+		return nil, fmt.Errorf("xigua %w", err)
+	}
+	item := videoInfoRes.Get("item_list.0")
+	if !item.Exists() {
+		return nil, fmt.Errorf("%w: xigua video %s", ErrContentUnavailable, videoId)
+	}
+
+	parseInfo := &models.VideoParseInfo{
+		Title:    item.Get("desc").String(),
+		VideoUrl: item.Get("video.play_addr.url_list.0").String(),
+		CoverUrl: item.Get("video.cover.url_list.0").String(),
+		MusicUrl: item.Get("music.play_url.url_list.0").String(),
+	}
+	parseInfo.Author.Uid = item.Get("author.sec_uid").String()
+	parseInfo.Author.Name = item.Get("author.nickname").String()
+	parseInfo.Author.Avatar = item.Get("author.avatar_thumb.url_list.0").String()
+	if parseInfo.VideoUrl == "" {
+		return nil, fmt.Errorf("%w: xigua parse video url", ErrParseFailed)
+	}
+	return parseInfo, nil
+}
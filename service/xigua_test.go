@@ -0,0 +1,46 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestXiGuaParseShareUrl(t *testing.T) {
+	fakePlatforms(t, map[string]http.HandlerFunc{
+		"v.ixigua.com/iJk7aBc/": redirectTo("https://www.ixigua.com/7312345678901234567?logTag=a1b2c3&utm_source=copy_link"),
+		"m.ixigua.com/douyin/share/video/7312345678901234567": func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("aweme_type") != "107" {
+				t.Errorf("aweme_type = %q", r.URL.Query().Get("aweme_type"))
+			}
+			serveFixture(t, "xigua_video.html")(w, r)
+		},
+	})
+
+	parseInfo, err := xiGua{}.ParseShareUrl(context.Background(), "https://v.ixigua.com/iJk7aBc/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parseInfo.Title != "三分钟看懂量子计算" {
+		t.Errorf("title = %q", parseInfo.Title)
+	}
+	if parseInfo.VideoUrl != "https://v3-xg-web.ixigua.com/abc123/video/tos/cn/main.mp4" {
+		t.Errorf("video url = %q", parseInfo.VideoUrl)
+	}
+	if parseInfo.Author.Name != "科普君" {
+		t.Errorf("author = %+v", parseInfo.Author)
+	}
+}
+
+func TestXiGuaParseVideoIDRemoved(t *testing.T) {
+	// 与抖音分享页结构相同, 作品被删除时 item_list 为空
+	fakePlatforms(t, map[string]http.HandlerFunc{
+		"m.ixigua.com/douyin/share/video/7300000000000000000": serveFixture(t, "douyin_removed.html"),
+	})
+
+	_, err := xiGua{}.ParseVideoID(context.Background(), "7300000000000000000")
+	if !errors.Is(err, ErrContentUnavailable) {
+		t.Fatalf("err = %v, want ErrContentUnavailable", err)
+	}
+}
@@ -0,0 +1,23 @@
+package settings
+
+import "github.com/gofiber/fiber/v2"
+
+// HeaderClientID 客户端标识请求头, 由网关设置时可用于功能开关按请求方灰度
+const HeaderClientID = "X-Client-ID"
+
+// Middleware 在请求上下文中保存请求方标识, 使同一客户端的灰度结果保持一致
+//
+// 默认使用服务端得到的客户端IP, 客户端可以随意修改请求头来选择灰度分组; 只有网关会覆盖 X-Client-ID
+// (如填入登录用户id) 时才应设置 trustClientID, 此时该请求头作为稳定的分桶依据, 未携带时仍使用客户端IP
+func Middleware(trustClientID bool) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		subject := ctx.IP()
+		if trustClientID {
+			if clientID := ctx.Get(HeaderClientID); clientID != "" {
+				subject = clientID
+			}
+		}
+		ctx.SetUserContext(WithSubject(ctx.UserContext(), subject))
+		return ctx.Next()
+	}
+}
@@ -0,0 +1,47 @@
+package settings
+
+import (
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func subjectOf(t *testing.T, trustClientID bool, clientID string) string {
+	t.Helper()
+	app := fiber.New()
+	app.Use(Middleware(trustClientID))
+	app.Get("/", func(ctx *fiber.Ctx) error {
+		return ctx.SendString(Subject(ctx.UserContext()))
+	})
+	req := httptest.NewRequest("GET", "/", nil)
+	if clientID != "" {
+		req.Header.Set(HeaderClientID, clientID)
+	}
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	return string(body)
+}
+
+func TestMiddlewareIgnoresUntrustedClientID(t *testing.T) {
+	ip := subjectOf(t, false, "")
+	if ip == "" {
+		t.Fatal("subject is empty")
+	}
+	if got := subjectOf(t, false, "lucky-bucket"); got != ip {
+		t.Errorf("subject = %q, want client ip %q", got, ip)
+	}
+}
+
+func TestMiddlewareTrustedClientID(t *testing.T) {
+	if got := subjectOf(t, true, "user-42"); got != "user-42" {
+		t.Errorf("subject = %q, want user-42", got)
+	}
+	if got := subjectOf(t, true, ""); got != subjectOf(t, false, "") {
+		t.Errorf("subject without header = %q, want client ip", got)
+	}
+}
@@ -0,0 +1,74 @@
+package settings
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+func TestFlagUnmarshalDefaultsToEnabled(t *testing.T) {
+	var flag Flag
+	if err := json.Unmarshal([]byte(`{"platforms":{"douyin":false}}`), &flag); err != nil {
+		t.Fatal(err)
+	}
+	if !flag.Enabled {
+		t.Fatal("flag without enabled should be enabled")
+	}
+
+	s := &Settings{Flags: map[string]Flag{"parse": flag}}
+	if s.FlagEnabled("parse", "douyin", "client") {
+		t.Error("parse should be disabled for douyin")
+	}
+	if !s.FlagEnabled("parse", "bilibili", "client") {
+		t.Error("parse should stay enabled for other platforms")
+	}
+}
+
+func TestFlagUnmarshalExplicitlyDisabled(t *testing.T) {
+	var flag Flag
+	if err := json.Unmarshal([]byte(`{"enabled":false}`), &flag); err != nil {
+		t.Fatal(err)
+	}
+	if flag.Enabled {
+		t.Fatal("enabled=false should disable the flag")
+	}
+}
+
+func TestFlagUnmarshalRejectsUnknownFields(t *testing.T) {
+	var flag Flag
+	if err := json.Unmarshal([]byte(`{"enable":false}`), &flag); err == nil {
+		t.Fatal("expected error for unknown field")
+	}
+}
+
+func TestApplyFlagsWithPlatformsOnly(t *testing.T) {
+	next, err := apply(Defaults(), "flags", json.RawMessage(`{"parse":{"platforms":{"douyin":false}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next.FlagEnabled("parse", "douyin", "client") {
+		t.Error("parse should be disabled for douyin")
+	}
+	if !next.FlagEnabled("parse", "kuaishou", "client") {
+		t.Error("parse should stay enabled for kuaishou")
+	}
+}
+
+func TestFlagRollout(t *testing.T) {
+	rollout := 30
+	s := &Settings{Flags: map[string]Flag{"media_proxy": {Enabled: true, Rollout: &rollout}}}
+	enabled := 0
+	for i := 0; i < 1000; i++ {
+		subject := fmt.Sprintf("client-%d", i)
+		if s.FlagEnabled("media_proxy", "", subject) {
+			enabled++
+		}
+		// 同一请求方的结果固定
+		if s.FlagEnabled("media_proxy", "", subject) != s.FlagEnabled("media_proxy", "", subject) {
+			t.Fatal("rollout should be stable for a subject")
+		}
+	}
+	if enabled < 200 || enabled > 400 {
+		t.Errorf("rollout 30%% enabled %d of 1000", enabled)
+	}
+}
@@ -0,0 +1,256 @@
+package settings
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/models"
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/redis/go-redis/v9"
+)
+
+// changedChannel 配置修改后发布通知的Redis频道, 各实例收到后重新加载
+const changedChannel = "settings:changed"
+
+// legacyFeaturesKey 旧版本的功能开关配置项, 值为 功能名: 是否开启, 已由 flags 代替
+const legacyFeaturesKey = "features"
+
+var (
+	// ErrUnknownSetting 配置项不存在
+	ErrUnknownSetting = errors.New("unknown setting")
+	// ErrInvalidSetting 配置项的值无效
+	ErrInvalidSetting = errors.New("invalid setting")
+)
+
+// Store 运行时配置的持久化存储
+type Store interface {
+	ListSettings(ctx context.Context) ([]*models.Setting, error)
+	SaveSetting(ctx context.Context, setting *models.Setting) error
+	DeleteSetting(ctx context.Context, key string) error
+}
+
+// Manager 在进程内缓存运行时配置, 配置保存在数据库中, 通过Redis发布订阅在多个实例间同步
+type Manager struct {
+	store   Store
+	redis   redis.UniversalClient
+	current atomic.Pointer[Settings]
+}
+
+func NewManager(store Store, redis redis.UniversalClient) *Manager {
+	m := &Manager{store: store, redis: redis}
+	m.current.Store(Defaults())
+	return m
+}
+
+// Get 返回当前生效的配置, 返回值只读, 未设置 Manager 时返回默认配置
+func (m *Manager) Get() *Settings {
+	if m == nil {
+		return Defaults()
+	}
+	return m.current.Load()
+}
+
+// Load 从数据库重新加载配置, 无法解析或校验失败的配置项保持默认值
+func (m *Manager) Load(ctx context.Context) error {
+	rows, err := m.store.ListSettings(ctx)
+	if err != nil {
+		return err
+	}
+	settings := Defaults()
+	var legacyFeatures *models.Setting
+	for _, row := range rows {
+		if row.Key == legacyFeaturesKey {
+			legacyFeatures = row
+			continue
+		}
+		next, err := apply(settings, row.Key, json.RawMessage(row.Value))
+		if err != nil {
+			log.Warnw("ignore invalid setting", "key", row.Key, "error", err)
+			continue
+		}
+		settings = next
+	}
+	if legacyFeatures != nil {
+		settings = m.migrateFeatures(ctx, settings, legacyFeatures)
+	}
+	m.current.Store(settings)
+	return nil
+}
+
+// migrateFeatures 将旧版本的 features 转换为 flags 中的总开关并保存, 已在 flags 中配置的功能以 flags 为准
+func (m *Manager) migrateFeatures(ctx context.Context, settings *Settings, row *models.Setting) *Settings {
+	var features map[string]bool
+	if err := json.Unmarshal([]byte(row.Value), &features); err != nil {
+		log.Warnw("ignore invalid setting", "key", row.Key, "error", err)
+		return settings
+	}
+	flags := maps.Clone(settings.Flags)
+	if flags == nil {
+		flags = map[string]Flag{}
+	}
+	for name, enabled := range features {
+		if _, ok := flags[name]; !ok {
+			flags[name] = Flag{Enabled: enabled}
+		}
+	}
+	value, err := json.Marshal(flags)
+	if err != nil {
+		log.Warnw("migrate features failed", "error", err)
+		return settings
+	}
+	next, err := apply(settings, "flags", value)
+	if err != nil {
+		log.Warnw("migrate features failed", "error", err)
+		return settings
+	}
+	// 保存失败时本次仍使用转换后的开关, 下次加载时重试
+	if err := m.store.SaveSetting(ctx, &models.Setting{Key: "flags", Value: string(value)}); err != nil {
+		log.Warnw("save migrated flags failed", "error", err)
+	} else if err := m.store.DeleteSetting(ctx, legacyFeaturesKey); err != nil {
+		log.Warnw("delete legacy features failed", "error", err)
+	}
+	return next
+}
+
+// Set 校验并保存配置项, 通知所有实例重新加载, 返回修改后的配置
+func (m *Manager) Set(ctx context.Context, key string, value json.RawMessage) (*Settings, error) {
+	if _, err := apply(m.Get(), key, value); err != nil {
+		return nil, err
+	}
+	if err := m.store.SaveSetting(ctx, &models.Setting{Key: key, Value: string(value)}); err != nil {
+		return nil, err
+	}
+	return m.reload(ctx)
+}
+
+// Reset 删除配置项使其恢复默认值, 通知所有实例重新加载, 返回修改后的配置
+func (m *Manager) Reset(ctx context.Context, key string) (*Settings, error) {
+	if !isKey(key) {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownSetting, key)
+	}
+	if err := m.store.DeleteSetting(ctx, key); err != nil {
+		return nil, err
+	}
+	return m.reload(ctx)
+}
+
+// SetFlag 新增或修改单个功能开关, 返回修改后的配置
+func (m *Manager) SetFlag(ctx context.Context, name string, flag Flag) (*Settings, error) {
+	flags := maps.Clone(m.Get().Flags)
+	if flags == nil {
+		flags = map[string]Flag{}
+	}
+	flags[name] = flag
+	return m.setFlags(ctx, flags)
+}
+
+// DeleteFlag 删除单个功能开关使其恢复默认开启, 返回修改后的配置
+func (m *Manager) DeleteFlag(ctx context.Context, name string) (*Settings, error) {
+	flags := maps.Clone(m.Get().Flags)
+	if _, ok := flags[name]; !ok {
+		return nil, fmt.Errorf("%w: flag %s", ErrUnknownSetting, name)
+	}
+	delete(flags, name)
+	return m.setFlags(ctx, flags)
+}
+
+func (m *Manager) setFlags(ctx context.Context, flags map[string]Flag) (*Settings, error) {
+	value, err := json.Marshal(flags)
+	if err != nil {
+		return nil, err
+	}
+	return m.Set(ctx, "flags", value)
+}
+
+// reload 重新加载本实例的配置并通知其他实例
+func (m *Manager) reload(ctx context.Context) (*Settings, error) {
+	if err := m.Load(ctx); err != nil {
+		return nil, err
+	}
+	if err := m.redis.Publish(ctx, changedChannel, "").Err(); err != nil {
+		log.WithContext(ctx).Warnw("publish settings change failed", "error", err)
+	}
+	return m.Get(), nil
+}
+
+// Start 订阅配置修改通知, 并每隔 interval 重新加载一次, 避免错过通知, ctx 取消后停止
+func (m *Manager) Start(ctx context.Context, interval time.Duration) {
+	pubsub := m.redis.Subscribe(ctx, changedChannel)
+	go func() {
+		defer pubsub.Close()
+		var tick <-chan time.Time
+		if interval > 0 {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			tick = ticker.C
+		}
+		messages := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-messages:
+			case <-tick:
+			}
+			if err := m.Load(ctx); err != nil {
+				log.Errorw("reload settings failed", "error", err)
+			}
+		}
+	}()
+}
+
+// apply 返回将配置项设为 value 后的新配置, 不修改 settings
+//
+// 配置项先恢复为默认值再解析 value, 映射类型的配置项与默认值合并
+func apply(settings *Settings, key string, value json.RawMessage) (*Settings, error) {
+	index := slices.Index(Keys(), key)
+	if index < 0 {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownSetting, key)
+	}
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return nil, err
+	}
+	next := &Settings{}
+	if err := json.Unmarshal(data, next); err != nil {
+		return nil, err
+	}
+	reflect.ValueOf(next).Elem().Field(index).Set(reflect.ValueOf(Defaults()).Elem().Field(index))
+	field, err := json.Marshal(map[string]json.RawMessage{key: value})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidSetting, key, err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(field))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(next); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidSetting, key, err)
+	}
+	if err := next.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSetting, err)
+	}
+	return next, nil
+}
+
+// Keys 返回所有配置项名称
+func Keys() []string {
+	t := reflect.TypeOf(Settings{})
+	keys := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		keys = append(keys, name)
+	}
+	return keys
+}
+
+func isKey(key string) bool {
+	return slices.Contains(Keys(), key)
+}
@@ -0,0 +1,75 @@
+package settings
+
+import (
+	"context"
+	"testing"
+
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/models"
+)
+
+// memoryStore 保存在内存中的配置存储
+type memoryStore struct {
+	rows map[string]string
+}
+
+func (s *memoryStore) ListSettings(ctx context.Context) ([]*models.Setting, error) {
+	var rows []*models.Setting
+	for key, value := range s.rows {
+		rows = append(rows, &models.Setting{Key: key, Value: value})
+	}
+	return rows, nil
+}
+
+func (s *memoryStore) SaveSetting(ctx context.Context, setting *models.Setting) error {
+	s.rows[setting.Key] = setting.Value
+	return nil
+}
+
+func (s *memoryStore) DeleteSetting(ctx context.Context, key string) error {
+	delete(s.rows, key)
+	return nil
+}
+
+func TestLoadMigratesLegacyFeatures(t *testing.T) {
+	store := &memoryStore{rows: map[string]string{
+		"features": `{"media_proxy":false,"convert_office":false}`,
+		"flags":    `{"convert_office":{"enabled":true}}`,
+	}}
+	m := NewManager(store, nil)
+	if err := m.Load(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	current := m.Get()
+	if current.FlagEnabled("media_proxy", "", "client") {
+		t.Error("media_proxy disabled in features should stay disabled")
+	}
+	if !current.FlagEnabled("convert_office", "", "client") {
+		t.Error("flags should take precedence over features")
+	}
+	if _, ok := store.rows["features"]; ok {
+		t.Error("features row should be deleted after migration")
+	}
+
+	// 迁移后重新加载结果不变
+	if err := m.Load(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if m.Get().FlagEnabled("media_proxy", "", "client") {
+		t.Error("migrated flag should be persisted")
+	}
+}
+
+func TestLoadIgnoresInvalidSettings(t *testing.T) {
+	store := &memoryStore{rows: map[string]string{
+		"rate_limit": `-1`,
+		"unknown":    `1`,
+	}}
+	m := NewManager(store, nil)
+	if err := m.Load(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if m.Get().RateLimit != 0 {
+		t.Errorf("invalid rate_limit should keep default, got %d", m.Get().RateLimit)
+	}
+}
@@ -0,0 +1,142 @@
+package settings
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// DefaultTranscodeProfile 未指定转码参数时使用的配置名
+const DefaultTranscodeProfile = "default"
+
+// x264Presets libx264 支持的编码速度
+var x264Presets = []string{"ultrafast", "superfast", "veryfast", "faster", "fast", "medium", "slow", "slower", "veryslow"}
+
+// Settings 运行时配置, 修改后各实例无需重启即可生效
+//
+// 每个字段为一个配置项, 配置项名称为字段的json名称
+type Settings struct {
+	// RateLimit 每个客户端IP每分钟允许的请求数, 0 表示不限制
+	RateLimit int `json:"rate_limit"`
+	// ProxyAllowedHosts 媒体代理允许访问的域名, 包含其子域名, 为空时只允许已支持平台的域名
+	ProxyAllowedHosts []string `json:"proxy_allowed_hosts"`
+	// UserAgents 各平台解析时使用的User-Agent, 多个时随机选择, 未配置的平台使用内置UA
+	UserAgents map[string][]string `json:"user_agents"`
+	// TranscodeProfiles 视频转码参数, 与默认的 default 合并
+	TranscodeProfiles map[string]TranscodeProfile `json:"transcode_profiles"`
+	// Flags 功能开关, 支持按平台关闭和按请求方灰度, 未配置的功能默认开启
+	Flags map[string]Flag `json:"flags"`
+}
+
+// TranscodeProfile 视频转码参数
+type TranscodeProfile struct {
+	Preset    string `json:"preset"`     // libx264 编码速度
+	CRF       int    `json:"crf"`        // 画质, 0-51, 越小画质越好
+	MaxHeight int    `json:"max_height"` // 超过该高度时等比缩小, 0 表示不缩放
+}
+
+// Defaults 返回默认配置, 与未引入运行时配置前的行为一致
+func Defaults() *Settings {
+	return &Settings{
+		TranscodeProfiles: map[string]TranscodeProfile{
+			DefaultTranscodeProfile: {Preset: "fast", CRF: 23},
+		},
+	}
+}
+
+// Validate 检查配置项的取值
+func (s *Settings) Validate() error {
+	var errs []error
+	if s.RateLimit < 0 {
+		errs = append(errs, fmt.Errorf("rate_limit: must not be negative"))
+	}
+	for _, host := range s.ProxyAllowedHosts {
+		if strings.TrimSpace(host) == "" || strings.Contains(host, "/") {
+			errs = append(errs, fmt.Errorf("proxy_allowed_hosts: invalid host %q", host))
+		}
+	}
+	for platform, userAgents := range s.UserAgents {
+		for _, userAgent := range userAgents {
+			if strings.TrimSpace(userAgent) == "" {
+				errs = append(errs, fmt.Errorf("user_agents.%s: user agent must not be empty", platform))
+			}
+		}
+	}
+	if _, ok := s.TranscodeProfiles[DefaultTranscodeProfile]; !ok {
+		errs = append(errs, fmt.Errorf("transcode_profiles: %s profile is required", DefaultTranscodeProfile))
+	}
+	for name, profile := range s.TranscodeProfiles {
+		if !slices.Contains(x264Presets, profile.Preset) {
+			errs = append(errs, fmt.Errorf("transcode_profiles.%s: preset must be one of %s", name, strings.Join(x264Presets, ", ")))
+		}
+		if profile.CRF < 0 || profile.CRF > 51 {
+			errs = append(errs, fmt.Errorf("transcode_profiles.%s: crf must be between 0 and 51", name))
+		}
+		if profile.MaxHeight < 0 {
+			errs = append(errs, fmt.Errorf("transcode_profiles.%s: max_height must not be negative", name))
+		}
+	}
+	for name, flag := range s.Flags {
+		if !flagNameReg.MatchString(name) {
+			errs = append(errs, fmt.Errorf("flags: invalid flag name %q", name))
+		}
+		if err := flag.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("flags.%s: %v", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// AllowsProxyHost 判断媒体代理是否允许访问该域名, 未配置 proxy_allowed_hosts 时使用 defaults
+func (s *Settings) AllowsProxyHost(host string, defaults []string) bool {
+	allowedHosts := s.ProxyAllowedHosts
+	if len(allowedHosts) == 0 {
+		allowedHosts = defaults
+	}
+	host = strings.ToLower(host)
+	for _, allowed := range allowedHosts {
+		allowed = strings.ToLower(strings.TrimSpace(allowed))
+		if host == allowed || strings.HasSuffix(host, "."+allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// UserAgent 返回平台配置的User-Agent, 未配置时返回空字符串
+func (s *Settings) UserAgent(platform string) string {
+	userAgents := s.UserAgents[platform]
+	if len(userAgents) == 0 {
+		return ""
+	}
+	return userAgents[rand.IntN(len(userAgents))]
+}
+
+// TranscodeProfile 返回指定名称的转码参数, name 为空时返回默认参数
+func (s *Settings) TranscodeProfile(name string) (TranscodeProfile, bool) {
+	if name == "" {
+		name = DefaultTranscodeProfile
+	}
+	profile, ok := s.TranscodeProfiles[name]
+	return profile, ok
+}
+
+// VideoFilter 在 filter 之后追加缩放, 返回FFmpeg的 -vf 参数, 无需处理时返回空字符串
+func (p TranscodeProfile) VideoFilter(filter string) string {
+	if p.MaxHeight <= 0 {
+		return filter
+	}
+	scale := fmt.Sprintf("scale=-2:'min(ih,%d)'", p.MaxHeight)
+	if filter == "" {
+		return scale
+	}
+	return filter + "," + scale
+}
+
+// EncodeArgs 返回H.264视频编码参数
+func (p TranscodeProfile) EncodeArgs() []string {
+	return []string{"-c:v", "libx264", "-preset", p.Preset, "-crf", strconv.Itoa(p.CRF)}
+}
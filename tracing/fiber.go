@@ -0,0 +1,69 @@
+package tracing
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.39.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// headerCarrier 从fiber请求头中读取 traceparent 等传播字段
+type headerCarrier struct {
+	ctx *fiber.Ctx
+}
+
+func (c headerCarrier) Get(key string) string {
+	return c.ctx.Get(key)
+}
+
+func (c headerCarrier) Set(key, value string) {
+	c.ctx.Request().Header.Set(key, value)
+}
+
+func (c headerCarrier) Keys() []string {
+	var keys []string
+	c.ctx.Request().Header.VisitAll(func(key, _ []byte) {
+		keys = append(keys, string(key))
+	})
+	return keys
+}
+
+// Middleware 为每个请求创建server span, 并继承请求头中的W3C trace context
+//
+// 在app上全局注册, 所有路由都会生成按路由命名的span, handler不需要单独包装
+//
+// span通过 ctx.UserContext() 传递, handler应使用 UserContext 调用下游以形成完整的调用链
+func Middleware() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		parent := otel.GetTextMapPropagator().Extract(ctx.UserContext(), headerCarrier{ctx})
+		spanCtx, span := Tracer().Start(parent, ctx.Method()+" "+ctx.Path(),
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(ctx.Method()),
+				semconv.URLPath(ctx.Path()),
+				attribute.String("request.id", ctx.GetRespHeader(fiber.HeaderXRequestID)),
+			))
+		defer span.End()
+		ctx.SetUserContext(spanCtx)
+
+		err := ctx.Next()
+		// 错误响应由 ErrorHandler 写入, 先执行以获得最终状态码
+		if err != nil {
+			span.RecordError(err)
+			if handlerErr := ctx.App().ErrorHandler(ctx, err); handlerErr != nil {
+				ctx.Status(fiber.StatusInternalServerError)
+			}
+		}
+
+		status := ctx.Response().StatusCode()
+		span.SetName(ctx.Method() + " " + ctx.Route().Path)
+		span.SetAttributes(semconv.HTTPRoute(ctx.Route().Path), semconv.HTTPResponseStatusCode(status))
+		if status >= fiber.StatusInternalServerError {
+			span.SetStatus(codes.Error, utils.StatusMessage(status))
+		}
+		return nil
+	}
+}
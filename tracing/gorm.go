@@ -0,0 +1,65 @@
+package tracing
+
+import (
+	"errors"
+
+	semconv "go.opentelemetry.io/otel/semconv/v1.39.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// gormSpanKey 保存当前语句span的实例键
+const gormSpanKey = "tracing:span"
+
+// GormPlugin 返回为每条SQL语句创建span的GORM插件, 需要通过 db.WithContext(ctx) 传入调用链
+func GormPlugin() gorm.Plugin {
+	return gormPlugin{}
+}
+
+type gormPlugin struct{}
+
+func (gormPlugin) Name() string {
+	return "tracing"
+}
+
+func (gormPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	return errors.Join(
+		cb.Create().Before("gorm:create").Register("tracing:before_create", gormBefore("insert")),
+		cb.Create().After("gorm:create").Register("tracing:after_create", gormAfter),
+		cb.Query().Before("gorm:query").Register("tracing:before_query", gormBefore("select")),
+		cb.Query().After("gorm:query").Register("tracing:after_query", gormAfter),
+		cb.Update().Before("gorm:update").Register("tracing:before_update", gormBefore("update")),
+		cb.Update().After("gorm:update").Register("tracing:after_update", gormAfter),
+		cb.Delete().Before("gorm:delete").Register("tracing:before_delete", gormBefore("delete")),
+		cb.Delete().After("gorm:delete").Register("tracing:after_delete", gormAfter),
+		cb.Row().Before("gorm:row").Register("tracing:before_row", gormBefore("row")),
+		cb.Row().After("gorm:row").Register("tracing:after_row", gormAfter),
+		cb.Raw().Before("gorm:raw").Register("tracing:before_raw", gormBefore("raw")),
+		cb.Raw().After("gorm:raw").Register("tracing:after_raw", gormAfter),
+	)
+}
+
+func gormBefore(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		_, span := Tracer().Start(db.Statement.Context, "gorm "+operation,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(semconv.DBSystemNamePostgreSQL, semconv.DBOperationName(operation)))
+		db.InstanceSet(gormSpanKey, span)
+	}
+}
+
+// gormAfter 记录SQL语句和影响行数, SQL中的参数为占位符, 不包含查询值
+func gormAfter(db *gorm.DB) {
+	value, ok := db.InstanceGet(gormSpanKey)
+	if !ok {
+		return
+	}
+	span := value.(trace.Span)
+	span.SetAttributes(
+		semconv.DBQueryText(db.Statement.SQL.String()),
+		semconv.DBCollectionName(db.Statement.Table),
+		semconv.DBResponseReturnedRows(int(db.Statement.RowsAffected)),
+	)
+	End(span, ignoreError(db.Error, gorm.ErrRecordNotFound))
+}
@@ -0,0 +1,82 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.39.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName 本服务创建span使用的tracer名称
+const tracerName = "github.com/can4hou6joeng4/convenient-tools-project-v1-backend"
+
+// Config 链路追踪配置
+type Config struct {
+	Endpoint    string  // OTLP/HTTP 接收地址, 如 http://otel-collector:4318, 为空时不导出
+	ServiceName string  // 上报的服务名
+	SampleRatio float64 // 采样比例, 上游已采样的请求始终采样
+}
+
+// Init 设置全局的 TracerProvider 和 W3C trace context 传播, 返回退出前刷新并关闭导出器的方法
+//
+// Endpoint 为空时保持OpenTelemetry默认的no-op实现, 只解析和传递trace context, 不产生任何网络请求
+func Init(ctx context.Context, config Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	if config.Endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(config.Endpoint))
+	if err != nil {
+		return nil, fmt.Errorf("create otlp exporter: %w", err)
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(config.ServiceName)))
+	if err != nil {
+		return nil, fmt.Errorf("create trace resource: %w", err)
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(config.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Tracer 返回本服务的tracer
+func Tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// Start 创建内部span
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End 结束span, err 不为nil时记录错误并将状态设为失败
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// ignoreError 返回 ignore 中的错误视为正常结果, 如缓存未命中
+func ignoreError(err error, ignore ...error) error {
+	for _, target := range ignore {
+		if errors.Is(err, target) {
+			return nil
+		}
+	}
+	return err
+}
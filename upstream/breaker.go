@@ -0,0 +1,205 @@
+package upstream
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2/log"
+)
+
+// 熔断器状态
+const (
+	BreakerClosed   = "closed"    // 正常放行
+	BreakerOpen     = "open"      // 连续失败过多, 直接拒绝请求
+	BreakerHalfOpen = "half_open" // 熔断到期, 放行少量请求探测是否恢复
+)
+
+// ErrCircuitOpen 目标域名处于熔断状态, 请求未发出
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// BreakerConfig 熔断器配置, FailureThreshold <= 0 时不启用熔断
+type BreakerConfig struct {
+	FailureThreshold int           // 连续失败多少次后熔断
+	OpenDuration     time.Duration // 熔断持续时间, 到期后进入半开状态
+	HalfOpenRequests int           // 半开状态下同时放行的探测请求数
+}
+
+// BreakerState 单个域名的熔断器状态
+type BreakerState struct {
+	Host                string     `json:"host"`
+	State               string     `json:"state"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	OpenedAt            *time.Time `json:"opened_at,omitempty"` // 最近一次熔断时间
+	Trips               int64      `json:"trips"`               // 累计熔断次数
+}
+
+// breakerOutcome 请求结果对熔断器的影响
+type breakerOutcome int
+
+const (
+	breakerSuccess breakerOutcome = iota // 请求成功, 清零连续失败次数
+	breakerFailure                       // 请求失败, 累计连续失败次数
+	breakerSkipped                       // 请求未实际完成, 如等待并发槽或重试间隔时被取消, 只归还半开状态的探测名额
+)
+
+// breaker 单个域名的熔断器
+type breaker struct {
+	config BreakerConfig
+
+	mu       sync.Mutex
+	state    string
+	failures int
+	openedAt time.Time
+	probes   int // 半开状态下正在进行的探测请求数
+	trips    int64
+}
+
+// allow 判断请求是否可以发出, 返回本次请求是否为半开状态下的探测请求
+func (b *breaker) allow() (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == BreakerOpen {
+		if time.Since(b.openedAt) < b.config.OpenDuration {
+			return false, ErrCircuitOpen
+		}
+		b.state = BreakerHalfOpen
+		b.probes = 0
+	}
+	if b.state == BreakerHalfOpen {
+		if b.probes >= b.config.HalfOpenRequests {
+			return false, ErrCircuitOpen
+		}
+		b.probes++
+		return true, nil
+	}
+	return false, nil
+}
+
+// done 记录请求结果, 半开状态下探测成功则恢复, 失败则重新熔断; 返回状态变化前后的值
+func (b *breaker) done(probe bool, outcome breakerOutcome) (from, to string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	from = b.state
+	if probe && b.state == BreakerHalfOpen {
+		b.probes--
+	}
+	switch outcome {
+	case breakerSkipped:
+		return from, b.state
+	case breakerSuccess:
+		b.failures = 0
+		if b.state == BreakerHalfOpen {
+			b.state = BreakerClosed
+		}
+		return from, b.state
+	}
+	b.failures++
+	if b.state == BreakerHalfOpen || (b.state == BreakerClosed && b.failures >= b.config.FailureThreshold) {
+		b.state = BreakerOpen
+		b.openedAt = time.Now()
+		b.trips++
+	}
+	return from, b.state
+}
+
+func (b *breaker) snapshot(host string) BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	state := BreakerState{
+		Host:                host,
+		State:               b.state,
+		ConsecutiveFailures: b.failures,
+		Trips:               b.trips,
+	}
+	if b.state == BreakerOpen && time.Since(b.openedAt) >= b.config.OpenDuration {
+		state.State = BreakerHalfOpen
+	}
+	if !b.openedAt.IsZero() {
+		openedAt := b.openedAt
+		state.OpenedAt = &openedAt
+	}
+	return state
+}
+
+// breakers 按域名维护熔断器
+type breakers struct {
+	config BreakerConfig
+
+	mu    sync.Mutex
+	hosts map[string]*breaker
+}
+
+func newBreakers(config BreakerConfig) *breakers {
+	if config.OpenDuration <= 0 {
+		config.OpenDuration = 30 * time.Second
+	}
+	if config.HalfOpenRequests <= 0 {
+		config.HalfOpenRequests = 1
+	}
+	return &breakers{config: config, hosts: make(map[string]*breaker)}
+}
+
+// allow 判断发往 host 的请求是否可以发出, 可以时返回记录请求结果的方法, 未启用熔断时总是放行
+func (b *breakers) allow(host string) (func(outcome breakerOutcome), error) {
+	if b.config.FailureThreshold <= 0 {
+		return func(breakerOutcome) {}, nil
+	}
+	b.mu.Lock()
+	hb, ok := b.hosts[host]
+	if !ok {
+		hb = &breaker{config: b.config, state: BreakerClosed}
+		b.hosts[host] = hb
+	}
+	b.mu.Unlock()
+
+	probe, err := hb.allow()
+	if err != nil {
+		return nil, err
+	}
+	var once sync.Once
+	return func(outcome breakerOutcome) {
+		once.Do(func() {
+			if from, to := hb.done(probe, outcome); from != to {
+				log.Warnw("circuit breaker state changed", "upstream_host", host, "from", from, "to", to)
+			}
+		})
+	}, nil
+}
+
+// states 返回所有已请求过的域名的熔断器状态, 按域名排序
+func (b *breakers) states() []BreakerState {
+	b.mu.Lock()
+	hosts := make([]string, 0, len(b.hosts))
+	for host := range b.hosts {
+		hosts = append(hosts, host)
+	}
+	b.mu.Unlock()
+	sort.Strings(hosts)
+
+	states := make([]BreakerState, 0, len(hosts))
+	for _, host := range hosts {
+		b.mu.Lock()
+		hb := b.hosts[host]
+		b.mu.Unlock()
+		states = append(states, hb.snapshot(host))
+	}
+	return states
+}
+
+// breakerResult 判断一次请求对熔断器的影响: 网络错误、超时、429 和 5xx 计为失败; 调用方主动取消和拒绝访问的内网地址不计入
+func breakerResult(ctx context.Context, resp *http.Response, err error) breakerOutcome {
+	if err != nil {
+		if errors.Is(err, ErrPrivateAddress) || (ctx.Err() != nil && !errors.Is(ctx.Err(), context.DeadlineExceeded)) {
+			return breakerSkipped
+		}
+		return breakerFailure
+	}
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError {
+		return breakerFailure
+	}
+	return breakerSuccess
+}
@@ -0,0 +1,96 @@
+package upstream
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBreakerSkippedReturnsProbe(t *testing.T) {
+	b := newBreakers(BreakerConfig{FailureThreshold: 1, OpenDuration: 10 * time.Millisecond, HalfOpenRequests: 1})
+	done, err := b.allow("www.douyin.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	done(breakerFailure)
+	if _, err := b.allow("www.douyin.com"); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("err = %v, want ErrCircuitOpen", err)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	probe, err := b.allow("www.douyin.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.allow("www.douyin.com"); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("second probe err = %v, want ErrCircuitOpen", err)
+	}
+	// 未完成的请求不影响状态, 只归还探测名额
+	probe(breakerSkipped)
+	if state := b.states()[0].State; state != BreakerHalfOpen {
+		t.Fatalf("state = %s, want %s", state, BreakerHalfOpen)
+	}
+	probe, err = b.allow("www.douyin.com")
+	if err != nil {
+		t.Fatalf("probe slot not returned: %v", err)
+	}
+	probe(breakerSuccess)
+	if state := b.states()[0].State; state != BreakerClosed {
+		t.Fatalf("state = %s, want %s", state, BreakerClosed)
+	}
+}
+
+func TestTransportCanceledRequestDoesNotTripBreaker(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(server.Close)
+	client := New(Config{
+		Policies:       map[string]HostPolicy{"127.0.0.1": {MaxRetries: 3}},
+		RetryBaseDelay: time.Minute,
+		Breaker:        BreakerConfig{FailureThreshold: 1, OpenDuration: time.Minute},
+	})
+
+	// 第一次请求返回503后在重试等待中被取消, 不计为失败
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := client.Get(ctx, server.URL, nil); err == nil {
+		t.Fatal("expected error")
+	}
+	if got := requests.Load(); got != 1 {
+		t.Fatalf("requests = %d, want 1", got)
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := client.Get(ctx, server.URL, nil); errors.Is(err, ErrCircuitOpen) {
+		t.Fatal("breaker opened by a request canceled during backoff")
+	}
+	if got := requests.Load(); got != 2 {
+		t.Fatalf("requests = %d, want 2", got)
+	}
+}
+
+func TestTransportSkipsBreakerForHostsWithoutPolicy(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(server.Close)
+	client := New(Config{Breaker: BreakerConfig{FailureThreshold: 1, OpenDuration: time.Minute}})
+
+	for i := 0; i < 3; i++ {
+		resp, err := client.Get(context.Background(), server.URL, nil)
+		if err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+		resp.Body.Close()
+	}
+	if states := client.BreakerStates(); len(states) != 0 {
+		t.Errorf("breaker states = %+v, want none for hosts without policy", states)
+	}
+}
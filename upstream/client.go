@@ -0,0 +1,480 @@
+package upstream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/metrics"
+	"github.com/can4hou6joeng4/convenient-tools-project-v1-backend/tracing"
+	"github.com/go-resty/resty/v2"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.39.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Config 出站HTTP客户端配置
+type Config struct {
+	Default             HostPolicy            // 默认策略
+	Policies            map[string]HostPolicy // 域名 => 策略, 同时匹配子域名, 优先使用最长匹配
+	RetryBaseDelay      time.Duration         // 首次重试前的等待时间, 之后按指数增长
+	RetryMaxDelay       time.Duration         // 单次重试等待时间上限
+	MaxIdleConnsPerHost int                   // 每个域名保持的空闲连接数
+	ProxyPools          map[string]*ProxyPool // 代理池名称 => 代理池, 由策略的 ProxyPool 引用
+	Breaker             BreakerConfig         // 按域名熔断
+	Transport           *http.Transport       // 基础Transport, 为nil时使用 http.DefaultTransport 的副本; 用于自定义拨号和TLS配置
+	DenyPrivateNetworks bool                  // 拒绝访问内网、回环和链路本地地址, 避免经媒体代理等接口访问内部服务
+}
+
+// Validate 检查策略引用的代理池是否存在
+func (c Config) Validate() error {
+	check := func(name string, policy HostPolicy) error {
+		if policy.ProxyPool == "" || policy.ProxyPool == ProxyDirect {
+			return nil
+		}
+		if _, ok := c.ProxyPools[policy.ProxyPool]; !ok {
+			return fmt.Errorf("host policy %s uses unknown proxy pool %q", name, policy.ProxyPool)
+		}
+		return nil
+	}
+	if err := check("default", c.Default); err != nil {
+		return err
+	}
+	for host, policy := range c.Policies {
+		if err := check(host, policy); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Client 共享的出站HTTP客户端, 复用连接池并按域名应用超时、请求头、并发和重试策略
+type Client struct {
+	http     *http.Client
+	breakers *breakers
+}
+
+func New(config Config) *Client {
+	if config.RetryBaseDelay <= 0 {
+		config.RetryBaseDelay = 200 * time.Millisecond
+	}
+	if config.RetryMaxDelay < config.RetryBaseDelay {
+		config.RetryMaxDelay = config.RetryBaseDelay
+	}
+	if config.MaxIdleConnsPerHost <= 0 {
+		config.MaxIdleConnsPerHost = 16
+	}
+	base := config.Transport
+	if base == nil {
+		base = http.DefaultTransport.(*http.Transport)
+	}
+	base = base.Clone()
+	base.MaxIdleConns = 256
+	base.MaxIdleConnsPerHost = config.MaxIdleConnsPerHost
+	base.Proxy = proxyFromContext
+	if config.DenyPrivateNetworks {
+		base.DialContext = publicDialer().DialContext
+	}
+	breakers := newBreakers(config.Breaker)
+
+	return &Client{
+		breakers: breakers,
+		http: &http.Client{
+			Transport: &transport{
+				base:     base,
+				config:   config,
+				breakers: breakers,
+				slots:    make(map[string]chan struct{}),
+			},
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if len(via) >= 10 {
+					return errors.New("重定向次数过多")
+				}
+				return nil
+			},
+		},
+	}
+}
+
+// HTTPClient 返回底层的 http.Client, 调用方不应修改其字段
+func (c *Client) HTTPClient() *http.Client {
+	return c.http
+}
+
+// Resty 返回使用共享连接池和域名策略的resty客户端, 每次调用返回新的实例, 可以自由修改超时、跳转策略等设置
+func (c *Client) Resty() *resty.Client {
+	hc := *c.http
+	return resty.NewWithClient(&hc)
+}
+
+// BreakerStates 返回各域名的熔断器状态
+func (c *Client) BreakerStates() []BreakerState {
+	return c.breakers.states()
+}
+
+// Do 发送请求
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	return c.http.Do(req)
+}
+
+// Get 发送GET请求, header 中的值覆盖域名策略的默认请求头
+func (c *Client) Get(ctx context.Context, url string, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	return c.http.Do(req)
+}
+
+// StatusError 远程服务器返回非2xx状态码
+type StatusError struct {
+	URL        string
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("源服务器响应错误: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// GetFirst 依次请求候选地址, 返回第一个2xx响应及其地址; 全部失败时返回最后一个错误
+//
+// 非2xx响应以 *StatusError 返回, 便于调用方区分网络错误和服务器拒绝
+func (c *Client) GetFirst(ctx context.Context, urls []string, header http.Header) (*http.Response, string, error) {
+	if len(urls) == 0 {
+		return nil, "", errors.New("no url to request")
+	}
+	var lastErr error
+	for _, url := range urls {
+		resp, err := c.Get(ctx, url, header)
+		if err != nil {
+			lastErr = err
+			if ctx.Err() != nil {
+				break
+			}
+			continue
+		}
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			resp.Body.Close()
+			lastErr = &StatusError{URL: url, StatusCode: resp.StatusCode}
+			continue
+		}
+		return resp, url, nil
+	}
+	return nil, "", lastErr
+}
+
+type timeoutKey struct{}
+
+// WithTimeout 为单个请求覆盖域名策略的超时时间, timeout <= 0 时不设置超时, 适用于由调用方context控制时长的大文件下载
+func WithTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	return context.WithValue(ctx, timeoutKey{}, timeout)
+}
+
+// transport 按域名策略设置请求头、限制并发、熔断并重试失败的幂等请求
+type transport struct {
+	base     http.RoundTripper
+	config   Config
+	breakers *breakers
+
+	mu    sync.Mutex
+	slots map[string]chan struct{} // 策略域名 => 并发槽
+}
+
+// RoundTrip 为每个出站请求创建client span, 包含重试在内的整个过程
+//
+// 不向平台注入 traceparent 请求头: 第三方平台不会参与调用链, 多余的请求头反而可能被识别为爬虫
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, span := tracing.Tracer().Start(req.Context(), "HTTP "+req.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(req.Method),
+			semconv.ServerAddress(req.URL.Hostname()),
+			semconv.URLPath(req.URL.Path),
+		))
+	resp, err := t.roundTrip(req.WithContext(ctx))
+	if err == nil {
+		span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+		if resp.StatusCode >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, resp.Status)
+		}
+	}
+	tracing.End(span, err)
+	return resp, err
+}
+
+func (t *transport) roundTrip(req *http.Request) (*http.Response, error) {
+	host, policy := t.policy(req.URL.Hostname())
+
+	req = req.Clone(req.Context())
+	for key, value := range policy.Headers {
+		if req.Header.Get(key) == "" {
+			req.Header.Set(key, value)
+		}
+	}
+	if policy.Referer != "" && req.Header.Get("Referer") == "" {
+		req.Header.Set("Referer", policy.Referer)
+	}
+	if len(policy.UserAgents) > 0 && req.Header.Get("User-Agent") == "" {
+		req.Header.Set("User-Agent", policy.UserAgents[rand.IntN(len(policy.UserAgents))])
+	}
+
+	if timeout, ok := req.Context().Value(timeoutKey{}).(time.Duration); ok {
+		policy.Timeout = timeout
+	}
+
+	pool := t.config.ProxyPools[policy.ProxyPool]
+	if policy.ProxyPool != "" && policy.ProxyPool != ProxyDirect && pool == nil {
+		return nil, fmt.Errorf("unknown proxy pool %q", policy.ProxyPool)
+	}
+
+	// 指标按策略域名统计, 未匹配策略的域名由请求参数决定, 统一记为 other 避免标签数量无限增长
+	metricsHost := host
+	if metricsHost == "" {
+		metricsHost = "other"
+	}
+	// 熔断同样按策略域名统计; 未匹配策略的域名不熔断, 避免熔断器随请求的域名无限增长, 也避免个别地址失败影响其他域名
+	done := func(breakerOutcome) {}
+	if host != "" {
+		var err error
+		if done, err = t.breakers.allow(host); err != nil {
+			metrics.ObserveUpstream(metricsHost, "circuit_open", 0)
+			return nil, fmt.Errorf("%w: %s", err, host)
+		}
+	}
+
+	acquired, err := t.acquire(req.Context(), host, policy.MaxConcurrency)
+	if err != nil {
+		done(breakerSkipped)
+		return nil, err
+	}
+	// 已记录本次请求结果时 done 不再生效, 否则视为请求未完成
+	release := func() {
+		acquired()
+		done(breakerSkipped)
+	}
+
+	maxRetries := policy.MaxRetries
+	if maxRetries < 0 || !retryable(req) {
+		maxRetries = 0
+	}
+	for attempt := 0; ; attempt++ {
+		var proxy *proxyEntry
+		if pool != nil {
+			if proxy, err = pool.pick(req.URL.Hostname()); err != nil {
+				release()
+				return nil, err
+			}
+		}
+		start := time.Now()
+		resp, cancel, err := t.try(req, policy.Timeout, proxy)
+		if err != nil {
+			metrics.ObserveUpstream(metricsHost, "error", time.Since(start))
+		} else {
+			metrics.ObserveUpstream(metricsHost, strconv.Itoa(resp.StatusCode), time.Since(start))
+		}
+		retry := shouldRetry(req.Context(), resp, err)
+		// 剔除后的重试由 shouldRetry 决定: 连接失败和429会换用其他代理重试, 403 通常是地址本身无权访问, 不重试
+		if proxy != nil {
+			if reason := proxyFailure(host != "", resp, err); reason != "" {
+				pool.eject(proxy, reason)
+			}
+		}
+		if attempt >= maxRetries || !retry {
+			done(breakerResult(req.Context(), resp, err))
+			if err != nil {
+				release()
+				return nil, err
+			}
+			resp.Body = &releaseBody{ReadCloser: resp.Body, host: metricsHost, release: func() { cancel(); release() }}
+			return resp, nil
+		}
+
+		delay := t.backoff(attempt, resp)
+		retryAttrs := []attribute.KeyValue{attribute.Int("attempt", attempt+1), attribute.String("delay", delay.String())}
+		if err != nil {
+			retryAttrs = append(retryAttrs, attribute.String("error", err.Error()))
+		} else {
+			retryAttrs = append(retryAttrs, semconv.HTTPResponseStatusCode(resp.StatusCode))
+		}
+		trace.SpanFromContext(req.Context()).AddEvent("retry", trace.WithAttributes(retryAttrs...))
+		if resp != nil {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+			resp.Body.Close()
+		}
+		cancel()
+		if err := sleep(req.Context(), delay); err != nil {
+			release()
+			return nil, err
+		}
+		if req.GetBody != nil {
+			if req.Body, err = req.GetBody(); err != nil {
+				release()
+				return nil, err
+			}
+		}
+	}
+}
+
+// try 通过指定代理(为nil时直连)发送一次请求, 超时覆盖到响应体读取完成, 调用方负责调用返回的cancel
+func (t *transport) try(req *http.Request, timeout time.Duration, proxy *proxyEntry) (*http.Response, context.CancelFunc, error) {
+	ctx, cancel := req.Context(), context.CancelFunc(func() {})
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	}
+	if proxy != nil {
+		ctx = context.WithValue(ctx, proxyKey{}, proxy)
+		// 经代理时本地不连接目标地址, 拨号时无法检查, 提前解析目标域名
+		if t.config.DenyPrivateNetworks {
+			if err := checkPublicHost(ctx, req.URL.Hostname()); err != nil {
+				cancel()
+				return nil, func() {}, err
+			}
+		}
+	}
+	resp, err := t.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, func() {}, err
+	}
+	return resp, cancel, nil
+}
+
+// policy 返回域名匹配的策略及其对应的配置域名, 未匹配时返回默认策略
+func (t *transport) policy(host string) (string, HostPolicy) {
+	host = strings.ToLower(host)
+	matched := ""
+	for domain := range t.config.Policies {
+		if (host == domain || strings.HasSuffix(host, "."+domain)) && len(domain) > len(matched) {
+			matched = domain
+		}
+	}
+	if matched == "" {
+		return "", t.config.Default
+	}
+	return matched, t.config.Policies[matched].Merge(t.config.Default)
+}
+
+// acquire 获取域名的并发槽, 返回释放方法
+func (t *transport) acquire(ctx context.Context, host string, limit int) (func(), error) {
+	if limit <= 0 {
+		return func() {}, nil
+	}
+	t.mu.Lock()
+	slots, ok := t.slots[host]
+	if !ok {
+		slots = make(chan struct{}, limit)
+		t.slots[host] = slots
+	}
+	t.mu.Unlock()
+
+	select {
+	case slots <- struct{}{}:
+		var once sync.Once
+		return func() { once.Do(func() { <-slots }) }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// backoff 计算第 attempt 次重试前的等待时间, 优先使用服务器返回的 Retry-After
+func (t *transport) backoff(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+			return min(time.Duration(seconds)*time.Second, t.config.RetryMaxDelay)
+		}
+	}
+	delay := t.config.RetryBaseDelay << attempt
+	if delay <= 0 || delay > t.config.RetryMaxDelay {
+		delay = t.config.RetryMaxDelay
+	}
+	// 加入随机抖动, 避免多个请求同时重试
+	return delay/2 + rand.N(delay/2+1)
+}
+
+// retryable 只重试幂等请求, 且请求体可以重新获取
+func retryable(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+	}
+	return false
+}
+
+// shouldRetry 网络错误和 429/502/503/504 可以重试, 调用方取消请求时不再重试
+func shouldRetry(ctx context.Context, resp *http.Response, err error) bool {
+	if ctx.Err() != nil || errors.Is(err, ErrPrivateAddress) {
+		return false
+	}
+	if err != nil {
+		var netErr net.Error
+		return errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, context.DeadlineExceeded)
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// proxyFailure 判断代理是否应被剔除, 返回剔除原因
+//
+// 403 也可能是目标地址本身无权访问, 只有配置了策略的平台域名返回403时才认为代理被封禁
+func proxyFailure(platform bool, resp *http.Response, err error) string {
+	if err != nil {
+		// 只有连接代理失败才归咎于代理, 目标站点超时等错误不剔除
+		var opErr *net.OpError
+		if errors.As(err, &opErr) && (opErr.Op == "dial" || opErr.Op == "proxyconnect" || strings.HasPrefix(opErr.Op, "socks")) {
+			return err.Error()
+		}
+		return ""
+	}
+	if resp.StatusCode == http.StatusTooManyRequests || (platform && resp.StatusCode == http.StatusForbidden) {
+		return resp.Status
+	}
+	return ""
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// releaseBody 统计读取的字节数, 关闭时释放并发槽并结束超时计时
+type releaseBody struct {
+	io.ReadCloser
+	host    string
+	once    sync.Once
+	release func()
+}
+
+func (b *releaseBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		metrics.AddUpstreamBytes(b.host, n)
+	}
+	return n, err
+}
+
+func (b *releaseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
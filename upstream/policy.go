@@ -0,0 +1,103 @@
+package upstream
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HostPolicy 对某个域名发起请求时使用的策略, 零值字段表示沿用默认策略
+type HostPolicy struct {
+	Timeout        time.Duration     // 单次请求超时, 包含读取响应体的时间
+	Headers        map[string]string // 默认请求头, 不覆盖请求中已设置的值
+	Referer        string            // 请求未设置Referer时使用
+	UserAgents     []string          // 请求未设置User-Agent时从中随机选择
+	MaxConcurrency int               // 同时进行的请求数上限, 0 表示不限制
+	MaxRetries     int               // 幂等请求失败后的最大重试次数, 负数表示不重试
+	ProxyPool      string            // 使用的代理池名称, 为空沿用默认策略, direct 表示直连
+}
+
+// Merge 用 p 中的非零字段覆盖 base
+func (p HostPolicy) Merge(base HostPolicy) HostPolicy {
+	merged := base
+	if p.Timeout > 0 {
+		merged.Timeout = p.Timeout
+	}
+	if len(p.Headers) > 0 {
+		merged.Headers = make(map[string]string, len(base.Headers)+len(p.Headers))
+		for k, v := range base.Headers {
+			merged.Headers[k] = v
+		}
+		for k, v := range p.Headers {
+			merged.Headers[k] = v
+		}
+	}
+	if p.Referer != "" {
+		merged.Referer = p.Referer
+	}
+	if len(p.UserAgents) > 0 {
+		merged.UserAgents = p.UserAgents
+	}
+	if p.MaxConcurrency != 0 {
+		merged.MaxConcurrency = p.MaxConcurrency
+	}
+	if p.MaxRetries != 0 {
+		merged.MaxRetries = p.MaxRetries
+	}
+	if p.ProxyPool != "" {
+		merged.ProxyPool = p.ProxyPool
+	}
+	return merged
+}
+
+// ParsePolicies 解析域名策略配置
+//
+// 格式为 域名:键=值,键=值, 多个域名以分号分隔, 域名同时匹配其子域名, 例如:
+//
+//	xhscdn.com:timeout=20s,concurrency=8,retries=3;hdslb.com:referer=https://www.bilibili.com/
+//
+// 支持的键: timeout, concurrency, retries, referer, ua(可重复, 组成UA池), proxy(代理池名称或 direct)
+func ParsePolicies(spec string) (map[string]HostPolicy, error) {
+	policies := make(map[string]HostPolicy)
+	for _, item := range strings.Split(spec, ";") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		host, options, ok := strings.Cut(item, ":")
+		host = strings.ToLower(strings.TrimSpace(host))
+		if !ok || host == "" {
+			return nil, fmt.Errorf("invalid host policy %q", item)
+		}
+		policy := policies[host]
+		for _, option := range strings.Split(options, ",") {
+			key, value, ok := strings.Cut(strings.TrimSpace(option), "=")
+			if !ok {
+				return nil, fmt.Errorf("invalid host policy option %q for %s", option, host)
+			}
+			var err error
+			switch key {
+			case "timeout":
+				policy.Timeout, err = time.ParseDuration(value)
+			case "concurrency":
+				policy.MaxConcurrency, err = strconv.Atoi(value)
+			case "retries":
+				policy.MaxRetries, err = strconv.Atoi(value)
+			case "referer":
+				policy.Referer = value
+			case "ua":
+				policy.UserAgents = append(policy.UserAgents, value)
+			case "proxy":
+				policy.ProxyPool = value
+			default:
+				err = fmt.Errorf("unknown option %q", key)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("invalid host policy for %s: %v", host, err)
+			}
+		}
+		policies[host] = policy
+	}
+	return policies, nil
+}
@@ -0,0 +1,58 @@
+package upstream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+// ErrPrivateAddress 目标地址为内网、回环或链路本地地址, 请求未发出
+var ErrPrivateAddress = errors.New("private address not allowed")
+
+// checkPublicAddr 只允许公网地址
+func checkPublicAddr(addr netip.Addr) error {
+	addr = addr.Unmap()
+	if addr.IsPrivate() || addr.IsLoopback() || addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() || addr.IsUnspecified() {
+		return fmt.Errorf("%w: %s", ErrPrivateAddress, addr)
+	}
+	return nil
+}
+
+// publicDialer 返回拒绝连接内网地址的拨号器, 在解析域名后、建立连接前检查实际连接的地址, 重定向和DNS解析到内网的域名同样会被拒绝
+//
+// 经代理的请求连接的是代理地址, 代理通常部署在内网, 不做检查; 目标地址由 checkPublicHost 检查
+func publicDialer() *net.Dialer {
+	return &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		ControlContext: func(ctx context.Context, network, address string, _ syscall.RawConn) error {
+			if _, ok := ctx.Value(proxyKey{}).(*proxyEntry); ok {
+				return nil
+			}
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			return checkPublicAddr(addrPort.Addr())
+		},
+	}
+}
+
+// checkPublicHost 解析经代理请求的目标域名, 任一地址为内网地址时拒绝请求
+func checkPublicHost(ctx context.Context, host string) error {
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return err
+	}
+	for _, addr := range addrs {
+		if err := checkPublicAddr(addr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
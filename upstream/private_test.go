@@ -0,0 +1,67 @@
+package upstream
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDenyPrivateNetworksDirect(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request reached loopback server")
+	}))
+	t.Cleanup(server.Close)
+	client := New(Config{Default: HostPolicy{MaxRetries: 2}, DenyPrivateNetworks: true, RetryBaseDelay: time.Millisecond})
+
+	_, err := client.Get(context.Background(), server.URL, nil)
+	if !errors.Is(err, ErrPrivateAddress) {
+		t.Fatalf("err = %v, want ErrPrivateAddress", err)
+	}
+}
+
+func TestDenyPrivateNetworksFollowsRedirects(t *testing.T) {
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("redirect reached loopback server")
+	}))
+	t.Cleanup(internal.Close)
+	// 代理返回跳转到内网地址的响应, 跳转后的请求直连时在拨号时被拒绝
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, internal.URL, http.StatusFound)
+	}))
+	t.Cleanup(proxy.Close)
+	pool := newTestPool(t, ProxyRoundRobin, time.Minute, proxy.URL)
+	client := New(Config{
+		Policies:            map[string]HostPolicy{"8.8.8.8": {ProxyPool: "test"}},
+		ProxyPools:          map[string]*ProxyPool{"test": pool},
+		DenyPrivateNetworks: true,
+	})
+
+	_, err := client.Get(context.Background(), "http://8.8.8.8/video/1", nil)
+	if !errors.Is(err, ErrPrivateAddress) {
+		t.Fatalf("err = %v, want ErrPrivateAddress", err)
+	}
+	if !pool.hasAvailable() {
+		t.Error("proxy was ejected for a rejected redirect")
+	}
+}
+
+func TestDenyPrivateNetworksThroughProxy(t *testing.T) {
+	proxy, requests := newTestProxy(t, statusOf(http.StatusOK))
+	pool := newTestPool(t, ProxyRoundRobin, time.Minute, proxy.URL)
+	client := New(Config{
+		Default:             HostPolicy{ProxyPool: "test"},
+		ProxyPools:          map[string]*ProxyPool{"test": pool},
+		DenyPrivateNetworks: true,
+	})
+
+	_, err := client.Get(context.Background(), "http://10.0.0.1/admin", nil)
+	if !errors.Is(err, ErrPrivateAddress) {
+		t.Fatalf("err = %v, want ErrPrivateAddress", err)
+	}
+	if got := requests.Load(); got != 0 {
+		t.Errorf("proxy requests = %d, want 0", got)
+	}
+}
@@ -0,0 +1,269 @@
+package upstream
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestProxy 启动只处理HTTP目标的本地正向代理, status 为代理返回的状态码
+func newTestProxy(t *testing.T, status *atomic.Int32) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var requests atomic.Int32
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		// 正向代理收到的是目标站点的完整地址
+		if !r.URL.IsAbs() {
+			t.Errorf("proxy got non-proxy request %s", r.URL)
+		}
+		w.WriteHeader(int(status.Load()))
+		io.WriteString(w, r.URL.String())
+	}))
+	t.Cleanup(proxy.Close)
+	return proxy, &requests
+}
+
+func statusOf(code int) *atomic.Int32 {
+	var status atomic.Int32
+	status.Store(int32(code))
+	return &status
+}
+
+func newTestPool(t *testing.T, strategy string, ejectDuration time.Duration, proxyURLs ...string) *ProxyPool {
+	t.Helper()
+	pool, err := NewProxyPool("test", proxyURLs, strategy, ejectDuration)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pool
+}
+
+func TestProxyPoolRoundRobin(t *testing.T) {
+	pool := newTestPool(t, ProxyRoundRobin, time.Minute, "http://10.0.0.1:3128", "http://10.0.0.2:3128", "http://10.0.0.3:3128")
+	for i := 0; i < 6; i++ {
+		entry, err := pool.pick("www.douyin.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if entry != pool.proxies[i%3] {
+			t.Fatalf("pick %d = %s, want %s", i, entry.url, pool.proxies[i%3].url)
+		}
+	}
+
+	// 被剔除的代理跳过
+	pool.eject(pool.proxies[1], "test")
+	for i := 0; i < 4; i++ {
+		entry, err := pool.pick("www.douyin.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if entry == pool.proxies[1] {
+			t.Fatalf("pick %d returned ejected proxy", i)
+		}
+	}
+}
+
+func TestProxyPoolSticky(t *testing.T) {
+	pool := newTestPool(t, ProxySticky, time.Minute, "http://10.0.0.1:3128", "http://10.0.0.2:3128", "http://10.0.0.3:3128")
+	first, err := pool.pick("www.douyin.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		if entry, _ := pool.pick("www.douyin.com"); entry != first {
+			t.Fatalf("sticky pick changed from %s to %s", first.url, entry.url)
+		}
+	}
+
+	// 固定的代理被剔除后换用下一个, 之后仍保持固定
+	pool.eject(first, "test")
+	next, err := pool.pick("www.douyin.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next == first {
+		t.Fatal("sticky pick returned ejected proxy")
+	}
+	for i := 0; i < 5; i++ {
+		if entry, _ := pool.pick("www.douyin.com"); entry != next {
+			t.Fatalf("sticky pick changed from %s to %s", next.url, entry.url)
+		}
+	}
+}
+
+func TestProxyPoolNoProxyAvailable(t *testing.T) {
+	pool := newTestPool(t, ProxyRoundRobin, time.Minute, "http://10.0.0.1:3128", "http://10.0.0.2:3128")
+	for _, entry := range pool.proxies {
+		pool.eject(entry, "test")
+	}
+	if pool.hasAvailable() {
+		t.Error("hasAvailable = true after ejecting every proxy")
+	}
+	if _, err := pool.pick("www.douyin.com"); !errors.Is(err, ErrNoProxyAvailable) {
+		t.Fatalf("err = %v, want ErrNoProxyAvailable", err)
+	}
+}
+
+func TestProxyPoolRecoversAfterEjectDuration(t *testing.T) {
+	pool := newTestPool(t, ProxyRoundRobin, 50*time.Millisecond, "http://10.0.0.1:3128")
+	pool.eject(pool.proxies[0], "test")
+	if _, err := pool.pick("www.douyin.com"); !errors.Is(err, ErrNoProxyAvailable) {
+		t.Fatalf("err = %v, want ErrNoProxyAvailable while ejected", err)
+	}
+	time.Sleep(60 * time.Millisecond)
+	if _, err := pool.pick("www.douyin.com"); err != nil {
+		t.Fatalf("proxy not available after eject duration: %v", err)
+	}
+}
+
+func TestTransportEjectsRateLimitedProxy(t *testing.T) {
+	limited, limitedRequests := newTestProxy(t, statusOf(http.StatusTooManyRequests))
+	healthy, healthyRequests := newTestProxy(t, statusOf(http.StatusOK))
+	pool := newTestPool(t, ProxyRoundRobin, time.Minute, limited.URL, healthy.URL)
+	client := New(Config{
+		Default:        HostPolicy{ProxyPool: "test", MaxRetries: 1},
+		ProxyPools:     map[string]*ProxyPool{"test": pool},
+		RetryBaseDelay: time.Millisecond,
+	})
+
+	// 第一次选中被限流的代理, 剔除后换用另一个代理重试
+	resp, err := client.Get(context.Background(), "http://platform.test/video/1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200 from the healthy proxy", resp.StatusCode)
+	}
+	if pool.proxies[0].available(time.Now()) {
+		t.Error("rate limited proxy was not ejected")
+	}
+
+	for i := 0; i < 3; i++ {
+		resp, err := client.Get(context.Background(), "http://platform.test/video/1", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	if got := limitedRequests.Load(); got != 1 {
+		t.Errorf("rate limited proxy requests = %d, want 1", got)
+	}
+	if got := healthyRequests.Load(); got != 4 {
+		t.Errorf("healthy proxy requests = %d, want 4", got)
+	}
+}
+
+func TestTransportEjectsProxyForbiddenByPlatform(t *testing.T) {
+	blocked, blockedRequests := newTestProxy(t, statusOf(http.StatusForbidden))
+	healthy, healthyRequests := newTestProxy(t, statusOf(http.StatusOK))
+	pool := newTestPool(t, ProxyRoundRobin, time.Minute, blocked.URL, healthy.URL)
+	client := New(Config{
+		Policies:       map[string]HostPolicy{"platform.test": {ProxyPool: "test", MaxRetries: 1}},
+		ProxyPools:     map[string]*ProxyPool{"test": pool},
+		RetryBaseDelay: time.Millisecond,
+	})
+
+	// 403 不重试, 但平台域名返回403时剔除代理, 之后的请求使用另一个代理
+	resp, err := client.Get(context.Background(), "http://platform.test/video/1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("status = %d, want 403 without retry", resp.StatusCode)
+	}
+	if pool.proxies[0].available(time.Now()) {
+		t.Error("blocked proxy was not ejected")
+	}
+
+	for i := 0; i < 3; i++ {
+		resp, err := client.Get(context.Background(), "http://platform.test/video/1", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	if got := blockedRequests.Load(); got != 1 {
+		t.Errorf("blocked proxy requests = %d, want 1", got)
+	}
+	if got := healthyRequests.Load(); got != 3 {
+		t.Errorf("healthy proxy requests = %d, want 3", got)
+	}
+}
+
+func TestTransportKeepsProxyOnForbiddenFromOtherHost(t *testing.T) {
+	proxy, requests := newTestProxy(t, statusOf(http.StatusForbidden))
+	pool := newTestPool(t, ProxyRoundRobin, time.Minute, proxy.URL)
+	client := New(Config{
+		Default:        HostPolicy{ProxyPool: "test", MaxRetries: 1},
+		ProxyPools:     map[string]*ProxyPool{"test": pool},
+		RetryBaseDelay: time.Millisecond,
+	})
+
+	// 未配置策略的域名返回403可能是地址本身无权访问, 不剔除代理也不重试
+	resp, err := client.Get(context.Background(), "http://private.example.com/file", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("status = %d, want 403", resp.StatusCode)
+	}
+	if !pool.hasAvailable() {
+		t.Error("proxy was ejected for a 403 from a host without policy")
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("proxy requests = %d, want 1", got)
+	}
+}
+
+func TestProxyPoolCheckHealth(t *testing.T) {
+	healthyStatus := statusOf(http.StatusNoContent)
+	flakyStatus := statusOf(http.StatusBadGateway)
+	healthy, _ := newTestProxy(t, healthyStatus)
+	flaky, _ := newTestProxy(t, flakyStatus)
+	pool := newTestPool(t, ProxyRoundRobin, time.Minute, healthy.URL, flaky.URL)
+	const checkURL = "http://health.test/generate_204"
+
+	results := pool.CheckHealth(context.Background(), checkURL)
+	if err := results[pool.proxies[0].url.Redacted()]; err != nil {
+		t.Errorf("healthy proxy check error = %v", err)
+	}
+	if err := results[pool.proxies[1].url.Redacted()]; err == nil {
+		t.Error("flaky proxy check succeeded, want error")
+	}
+	if pool.proxies[1].available(time.Now()) {
+		t.Error("failed proxy was not ejected")
+	}
+
+	// 恢复后下一次检查重新启用, 不需要等待剔除时间结束
+	flakyStatus.Store(http.StatusNoContent)
+	results = pool.CheckHealth(context.Background(), checkURL)
+	if err := results[pool.proxies[1].url.Redacted()]; err != nil {
+		t.Errorf("recovered proxy check error = %v", err)
+	}
+	if !pool.proxies[1].available(time.Now()) {
+		t.Error("recovered proxy is still ejected")
+	}
+}
+
+func TestProxyPoolCheckHealthUnreachableProxy(t *testing.T) {
+	unreachable := httptest.NewServer(http.NotFoundHandler())
+	unreachable.Close()
+	pool := newTestPool(t, ProxyRoundRobin, time.Minute, unreachable.URL)
+
+	results := pool.CheckHealth(context.Background(), "http://health.test/generate_204")
+	if err := results[pool.proxies[0].url.Redacted()]; err == nil {
+		t.Error("unreachable proxy check succeeded, want error")
+	}
+	if pool.hasAvailable() {
+		t.Error("unreachable proxy was not ejected")
+	}
+}